@@ -0,0 +1,192 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/beckxie/skype-history-viewer-cli/pkg/index"
+	"github.com/beckxie/skype-history-viewer-cli/pkg/models"
+	"github.com/beckxie/skype-history-viewer-cli/pkg/utils"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var (
+	missedCallsOnly bool
+)
+
+// conversationCalls are the calls of one conversation
+type conversationCalls struct {
+	Conversation string
+	Calls        []models.Call
+}
+
+// callsCmd represents the calls command
+var callsCmd = &cobra.Command{
+	Use:   "calls [conversation-number]",
+	Short: "List calls",
+	Long: `Display the call log of every conversation, or of a single one, with when each call started and ended,
+how long it lasted, who took part and whether it was missed.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Check if JSON path is provided
+		if err := checkJSONPath(); err != nil {
+			return err
+		}
+
+		var logs []conversationCalls
+		var err error
+		if len(args) > 0 {
+			num, convErr := strconv.Atoi(args[0])
+			if convErr != nil {
+				return fmt.Errorf("invalid conversation number: %s", args[0])
+			}
+			logs, err = loadConversationCalls(num)
+		} else {
+			logs, err = loadAllCalls()
+		}
+		if err != nil {
+			return err
+		}
+
+		displayCalls(logs, missedCallsOnly)
+		return nil
+	},
+}
+
+// loadConversationCalls returns the calls of the conversation with the
+// given 1-based number
+func loadConversationCalls(num int) ([]conversationCalls, error) {
+	catalog, err := openConversationCatalog()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load Skype history: %w", err)
+	}
+	if num < 1 || num > catalog.Len() {
+		return nil, fmt.Errorf("invalid conversation number: %d (valid range: 1-%d)", num, catalog.Len())
+	}
+
+	conv, err := catalog.Conversation(num)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load conversation: %w", err)
+	}
+	return []conversationCalls{{Conversation: conv.GetConversationDisplayName(), Calls: conv.Calls()}}, nil
+}
+
+// loadAllCalls returns the calls of every conversation holding any. The
+// index lets conversations without call events be skipped unread.
+func loadAllCalls() ([]conversationCalls, error) {
+	var logs []conversationCalls
+	add := func(conv *models.SkypeConversation) {
+		if calls := conv.Calls(); len(calls) > 0 {
+			logs = append(logs, conversationCalls{Conversation: conv.GetConversationDisplayName(), Calls: calls})
+		}
+	}
+
+	if noIndex {
+		err := utils.StreamSkypeHistory(jsonPath, loadOptions(), func(conv *models.SkypeConversation) error {
+			add(conv)
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to load Skype history: %w", err)
+		}
+		return logs, nil
+	}
+
+	idx, err := loadOrBuildIndex()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load Skype history: %w", err)
+	}
+	reader, err := idx.Reader(func(entry *index.ConversationEntry) bool {
+		return entry.MessageTypes[models.CallMessageType] > 0
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open Skype history: %w", err)
+	}
+	defer reader.Close()
+
+	for {
+		conv, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return logs, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read conversation: %w", err)
+		}
+		add(conv)
+	}
+}
+
+// displayCalls shows calls in a table followed by totals, leaving out
+// answered calls when missedOnly is set
+func displayCalls(logs []conversationCalls, missedOnly bool) {
+	table := newSearchTable([]string{"Conversation", "Start", "End", "Duration", "Participants", "Status"})
+
+	var count, missed int
+	var total time.Duration
+	for _, log := range logs {
+		for _, call := range log.Calls {
+			if missedOnly && !call.Missed {
+				continue
+			}
+			count++
+			if call.Missed {
+				missed++
+			}
+			total += call.Duration
+			table.Append(callRow(log.Conversation, call))
+		}
+	}
+
+	if count == 0 {
+		if missedOnly {
+			color.New(color.FgYellow).Println("No missed calls found")
+		} else {
+			color.New(color.FgYellow).Println("No calls found")
+		}
+		return
+	}
+
+	table.Render()
+	color.New(color.FgCyan).Printf("%d call(s), %d missed, %s in total\n", count, missed, formatCallDuration(total))
+}
+
+// callRow formats a call as a row of the calls table
+func callRow(conversation string, call models.Call) []string {
+	end, duration, status := "", "", "Answered"
+	switch {
+	case call.End.IsZero():
+		status = "No end recorded"
+	case call.Missed:
+		end, status = formatCallTime(call.End), "Missed"
+	default:
+		end, duration = formatCallTime(call.End), formatCallDuration(call.Duration)
+	}
+
+	return []string{conversation, formatCallTime(call.Start), end, duration, strings.Join(call.ParticipantNames(), ", "), status}
+}
+
+// formatCallTime formats a call time, or "" when unknown
+func formatCallTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("2006-01-02 15:04:05")
+}
+
+// formatCallDuration formats a duration as h:mm:ss
+func formatCallDuration(d time.Duration) string {
+	seconds := int64(d.Round(time.Second) / time.Second)
+	return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+}
+
+func init() {
+	rootCmd.AddCommand(callsCmd)
+
+	// Local flags
+	callsCmd.Flags().BoolVar(&missedCallsOnly, "missed", false, "Only show missed calls")
+}
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/beckxie/skype-history-viewer-cli/pkg/models"
+)
+
+func TestCallRow(t *testing.T) {
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	alice := models.CallParticipant{Id: "8:alice", Name: "Alice"}
+	bob := models.CallParticipant{Id: "8:bob"}
+
+	tests := []struct {
+		name string
+		call models.Call
+		want []string
+	}{
+		{
+			name: "answered",
+			call: models.Call{Start: start, End: start.Add(3725 * time.Second), Duration: 3725 * time.Second, Participants: []models.CallParticipant{alice, bob}},
+			want: []string{"Team", "2024-01-01 10:00:00", "2024-01-01 11:02:05", "1:02:05", "Alice, 8:bob", "Answered"},
+		},
+		{
+			name: "missed",
+			call: models.Call{Start: start, End: start, Participants: []models.CallParticipant{alice}, Missed: true},
+			want: []string{"Team", "2024-01-01 10:00:00", "2024-01-01 10:00:00", "", "Alice", "Missed"},
+		},
+		{
+			name: "no end",
+			call: models.Call{Start: start, Participants: []models.CallParticipant{alice}},
+			want: []string{"Team", "2024-01-01 10:00:00", "", "", "Alice", "No end recorded"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := callRow("Team", tt.call); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("callRow() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -34,19 +34,22 @@ var exportCmd = &cobra.Command{
 		}
 
 		// Load Skype history
-		history, err := utils.LoadSkypeHistory(jsonPath)
+		catalog, err := openConversationCatalog()
 		if err != nil {
 			return fmt.Errorf("failed to load Skype history: %w", err)
 		}
 
 		// Validate conversation number
-		if num > len(history.Conversations) {
+		if num > catalog.Len() {
 			return fmt.Errorf("conversation number %d not found (valid range: 1-%d)",
-				num, len(history.Conversations))
+				num, catalog.Len())
 		}
 
 		// Get conversation
-		conv := &history.Conversations[num-1]
+		conv, err := catalog.Conversation(num)
+		if err != nil {
+			return fmt.Errorf("failed to load conversation: %w", err)
+		}
 
 		// Generate output filename if not specified
 		if outputPath == "" {
@@ -71,7 +74,7 @@ var exportCmd = &cobra.Command{
 		}
 
 		// Export conversation with original userId
-		if err := utils.ExportConversation(conv, absPath, history.UserId); err != nil {
+		if err := utils.ExportConversation(conv, absPath, catalog.UserId); err != nil {
 			return fmt.Errorf("failed to export conversation: %w", err)
 		}
 
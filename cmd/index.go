@@ -0,0 +1,237 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/beckxie/skype-history-viewer-cli/pkg/index"
+	"github.com/beckxie/skype-history-viewer-cli/pkg/models"
+	"github.com/beckxie/skype-history-viewer-cli/pkg/utils"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+// indexCmd represents the index command
+var indexCmd = &cobra.Command{
+	Use:   "index",
+	Short: "Manage the on-disk index of an export",
+	Long: `Build, rebuild or inspect the index that stores conversation metadata,
+message offsets and pre-parsed timestamps of an export, so that repeated
+commands don't have to re-parse the whole JSON file. A full-text index of
+the messages is built alongside it for fast, relevance-ranked search.
+
+The index lives in the user cache directory and is invalidated automatically
+when the export's size, modification time or content hash changes.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+var indexBuildCmd = &cobra.Command{
+	Use:   "build",
+	Short: "Build the index if it is missing or out of date",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkJSONPath(); err != nil {
+			return err
+		}
+
+		idx, err := index.Load(jsonPath)
+		if err == nil {
+			color.New(color.FgGreen).Println("✓ Index is up to date")
+			displayIndexInfo(idx)
+			return nil
+		}
+		if !errors.Is(err, index.ErrNotFound) && !errors.Is(err, index.ErrStale) {
+			return fmt.Errorf("failed to load index: %w", err)
+		}
+
+		return buildIndex()
+	},
+}
+
+var indexRebuildCmd = &cobra.Command{
+	Use:   "rebuild",
+	Short: "Rebuild the index from scratch",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkJSONPath(); err != nil {
+			return err
+		}
+
+		if err := index.Remove(jsonPath); err != nil {
+			return err
+		}
+		return buildIndex()
+	},
+}
+
+var indexInfoCmd = &cobra.Command{
+	Use:   "info",
+	Short: "Show details about the index of an export",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkJSONPath(); err != nil {
+			return err
+		}
+
+		idx, err := index.Load(jsonPath)
+		switch {
+		case errors.Is(err, index.ErrNotFound):
+			color.New(color.FgYellow).Println("No index has been built for this export yet.")
+			return nil
+		case errors.Is(err, index.ErrStale):
+			color.New(color.FgYellow).Println("The index is out of date, run 'index build' to refresh it.")
+			return nil
+		case err != nil:
+			return fmt.Errorf("failed to load index: %w", err)
+		}
+
+		displayIndexInfo(idx)
+		return nil
+	},
+}
+
+// buildIndex builds and saves the index of the current export
+func buildIndex() error {
+	idx, err := index.Build(jsonPath, loadOptions())
+	if err != nil {
+		return fmt.Errorf("failed to build index: %w", err)
+	}
+	if err := idx.Save(jsonPath); err != nil {
+		return fmt.Errorf("failed to save index: %w", err)
+	}
+
+	fmt.Println()
+	color.New(color.FgGreen, color.Bold).Println("✓ Index built")
+	displayIndexInfo(idx)
+	return nil
+}
+
+// displayIndexInfo prints where an index is stored and what it contains
+func displayIndexInfo(idx *index.Index) {
+	indexPath, _ := index.Path(jsonPath)
+	fmt.Printf("  Index: %s\n", indexPath)
+	if info, err := os.Stat(indexPath); err == nil {
+		fmt.Printf("  Index size: %.2f MB\n", float64(info.Size())/(1024*1024))
+	}
+	fmt.Printf("  Source: %s\n", idx.SourceFile)
+	fmt.Printf("  Fingerprint: %s\n", index.FormatFingerprint(idx.Fingerprint))
+	fmt.Printf("  Built at: %s\n", idx.BuiltAt.Format("2006-01-02 15:04:05"))
+	fmt.Printf("  User ID: %s\n", idx.UserId)
+	fmt.Printf("  Conversations: %d\n", len(idx.Conversations))
+	fmt.Printf("  Total Messages: %d\n", idx.MessageCount())
+
+	segment, err := idx.OpenText(jsonPath)
+	if err != nil {
+		fmt.Println("  Text index: not available, run 'index rebuild' to create it")
+		return
+	}
+	defer segment.Close()
+
+	textPath, _ := index.TextPath(jsonPath)
+	fmt.Printf("  Text index: %s\n", textPath)
+	if info, err := os.Stat(textPath); err == nil {
+		fmt.Printf("  Text index size: %.2f MB\n", float64(info.Size())/(1024*1024))
+	}
+	fmt.Printf("  Indexed messages: %d\n", segment.DocCount())
+	fmt.Printf("  Distinct terms: %d\n", segment.TermCount())
+}
+
+// loadIndex returns a fresh index for the current export, or nil when
+// indexing is disabled or no up-to-date index exists
+func loadIndex() *index.Index {
+	return loadIndexAt(jsonPath)
+}
+
+// loadIndexAt returns a fresh index for the export at exportPath, or nil
+// when indexing is disabled or no up-to-date index exists
+func loadIndexAt(exportPath string) *index.Index {
+	if noIndex {
+		return nil
+	}
+	idx, err := index.Load(exportPath)
+	if err != nil {
+		return nil
+	}
+	return idx
+}
+
+// loadOrBuildIndex returns the index of the current export, building it
+// while reading the export when it is missing or stale. Failing to save the
+// index only produces a warning.
+func loadOrBuildIndex() (*index.Index, error) {
+	idx, err := index.Load(jsonPath)
+	if err == nil {
+		return idx, nil
+	}
+	if !errors.Is(err, index.ErrNotFound) && !errors.Is(err, index.ErrStale) {
+		return nil, err
+	}
+
+	idx, err = index.Build(jsonPath, loadOptions())
+	if err != nil {
+		return nil, err
+	}
+	if err := idx.Save(jsonPath); err != nil {
+		// Keep stdout clean for machine-readable output
+		color.New(color.FgYellow).Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	return idx, nil
+}
+
+// conversationCatalog gives view and export access to conversations by
+// number, through the index when available or a fully loaded history
+type conversationCatalog struct {
+	UserId    string
+	Summaries []models.ConversationSummary
+	load      func(i int) (*models.SkypeConversation, error)
+}
+
+// openConversationCatalog prefers an up-to-date index and falls back to
+// loading the whole export
+func openConversationCatalog() (*conversationCatalog, error) {
+	if idx := loadIndex(); idx != nil {
+		return &conversationCatalog{
+			UserId:    idx.UserId,
+			Summaries: idx.Summaries(),
+			load:      idx.ReadConversation,
+		}, nil
+	}
+
+	history, err := utils.LoadSkypeHistory(jsonPath, loadOptions())
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := make([]models.ConversationSummary, len(history.Conversations))
+	for i := range history.Conversations {
+		summaries[i] = history.Conversations[i].Summary()
+	}
+
+	return &conversationCatalog{
+		UserId:    history.UserId,
+		Summaries: summaries,
+		load: func(i int) (*models.SkypeConversation, error) {
+			return &history.Conversations[i], nil
+		},
+	}, nil
+}
+
+// Len returns the number of conversations
+func (c *conversationCatalog) Len() int {
+	return len(c.Summaries)
+}
+
+// Conversation returns the conversation with the given 1-based number
+func (c *conversationCatalog) Conversation(num int) (*models.SkypeConversation, error) {
+	if num < 1 || num > c.Len() {
+		return nil, fmt.Errorf("invalid conversation number: %d", num)
+	}
+	return c.load(num - 1)
+}
+
+func init() {
+	rootCmd.AddCommand(indexCmd)
+	indexCmd.AddCommand(indexBuildCmd)
+	indexCmd.AddCommand(indexRebuildCmd)
+	indexCmd.AddCommand(indexInfoCmd)
+}
@@ -3,6 +3,7 @@ package cmd
 import (
 	"fmt"
 
+	"github.com/beckxie/skype-history-viewer-cli/pkg/models"
 	"github.com/beckxie/skype-history-viewer-cli/pkg/utils"
 	"github.com/beckxie/skype-history-viewer-cli/pkg/viewer"
 	"github.com/spf13/cobra"
@@ -23,20 +24,34 @@ var listCmd = &cobra.Command{
 			return err
 		}
 
-		// Load Skype history
-		history, err := utils.LoadSkypeHistory(jsonPath)
-		if err != nil {
-			return fmt.Errorf("failed to load Skype history: %w", err)
-		}
-
 		// Create viewer with options
 		viewerOptions := viewer.ViewerOptions{
 			ShowSystemMessages: showSystem,
 		}
 		messageViewer := viewer.NewMessageViewer(viewerOptions)
 
+		// Summaries come straight from the index, which is built on first use
+		if !noIndex {
+			idx, err := loadOrBuildIndex()
+			if err != nil {
+				return fmt.Errorf("failed to load Skype history: %w", err)
+			}
+			messageViewer.DisplayConversationSummaries(idx.Summaries())
+			return nil
+		}
+
+		// Stream Skype history, keeping only a summary per conversation
+		var summaries []models.ConversationSummary
+		err := utils.StreamSkypeHistory(jsonPath, loadOptions(), func(conv *models.SkypeConversation) error {
+			summaries = append(summaries, conv.Summary())
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to load Skype history: %w", err)
+		}
+
 		// Display conversation list
-		messageViewer.DisplayConversationList(history.Conversations)
+		messageViewer.DisplayConversationSummaries(summaries)
 
 		return nil
 	},
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/beckxie/skype-history-viewer-cli/pkg/utils"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var (
+	mediaOutputPath string
+)
+
+// mediaCmd represents the media command
+var mediaCmd = &cobra.Command{
+	Use:   "media [file-name]",
+	Short: "List or extract the files in the media folder of an export",
+	Long: `List the files stored in the media/ folder of an export directory or .tar archive, or extract one of
+them without unpacking the whole archive.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Check if JSON path is provided
+		if err := checkJSONPath(); err != nil {
+			return err
+		}
+
+		if len(args) == 0 {
+			entries, err := utils.ListMedia(jsonPath)
+			if err != nil {
+				return fmt.Errorf("failed to list media: %w", err)
+			}
+			displayMedia(entries)
+			return nil
+		}
+
+		output := mediaOutputPath
+		if output == "" {
+			output = args[0]
+		}
+		written, err := extractMedia(jsonPath, args[0], output)
+		if err != nil {
+			return err
+		}
+
+		color.New(color.FgGreen).Printf("✓ Extracted %s (%d bytes) to: %s\n", args[0], written, output)
+		return nil
+	},
+}
+
+// extractMedia copies the media file name of an export to output
+func extractMedia(exportPath, name, output string) (int64, error) {
+	source, err := utils.OpenMedia(exportPath, name)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open media file: %w", err)
+	}
+	defer source.Close()
+
+	file, err := os.Create(output)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create output file: %w", err)
+	}
+
+	written, err := io.Copy(file, source)
+	if err != nil {
+		file.Close()
+		return 0, fmt.Errorf("failed to extract media file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return 0, fmt.Errorf("failed to write output file: %w", err)
+	}
+	return written, nil
+}
+
+// displayMedia prints a table of media files
+func displayMedia(entries []utils.MediaEntry) {
+	if len(entries) == 0 {
+		color.New(color.FgYellow).Println("No media files found")
+		return
+	}
+
+	fmt.Println()
+	table := newSearchTable([]string{"Name", "Size (bytes)"})
+	var total int64
+	for _, entry := range entries {
+		table.Append([]string{entry.Name, strconv.FormatInt(entry.Size, 10)})
+		total += entry.Size
+	}
+	table.Render()
+
+	color.New(color.FgCyan).Printf("\nTotal: %d files, %d bytes\n", len(entries), total)
+}
+
+func init() {
+	rootCmd.AddCommand(mediaCmd)
+
+	// Local flags
+	mediaCmd.Flags().StringVarP(&mediaOutputPath, "output", "o", "", "Output file path (default: the media file name)")
+}
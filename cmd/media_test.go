@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExtractMedia(t *testing.T) {
+	exportDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(exportDir, "media"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(exportDir, "media", "photo.jpg"), []byte("jpeg-bytes"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	output := filepath.Join(t.TempDir(), "copy.jpg")
+	written, err := extractMedia(exportDir, "photo.jpg", output)
+	if err != nil {
+		t.Fatalf("extractMedia error = %v", err)
+	}
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if written != 10 || string(data) != "jpeg-bytes" {
+		t.Errorf("unexpected extracted file: %d bytes, %q", written, data)
+	}
+
+	if _, err := extractMedia(exportDir, "missing.jpg", output); err == nil {
+		t.Error("expected error for a missing media file")
+	}
+}
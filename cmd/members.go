@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/beckxie/skype-history-viewer-cli/pkg/models"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+// membersHistoryCmd represents the members-history command
+var membersHistoryCmd = &cobra.Command{
+	Use:   "members-history <conversation-number>",
+	Short: "Show how a group's members and topic changed",
+	Long: `Reconstruct the timeline of a group conversation from its system messages: who added or removed whom,
+topic and picture changes, role changes, and who belonged to the group over time.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Check if JSON path is provided
+		if err := checkJSONPath(); err != nil {
+			return err
+		}
+
+		num, err := strconv.Atoi(args[0])
+		if err != nil {
+			return fmt.Errorf("invalid conversation number: %s", args[0])
+		}
+
+		catalog, err := openConversationCatalog()
+		if err != nil {
+			return fmt.Errorf("failed to load Skype history: %w", err)
+		}
+		if num < 1 || num > catalog.Len() {
+			return fmt.Errorf("invalid conversation number: %d (valid range: 1-%d)", num, catalog.Len())
+		}
+
+		conv, err := catalog.Conversation(num)
+		if err != nil {
+			return fmt.Errorf("failed to load conversation: %w", err)
+		}
+
+		displayMembersHistory(conv)
+		return nil
+	},
+}
+
+// displayMembersHistory prints the group events of conv followed by a table
+// of its memberships
+func displayMembersHistory(conv *models.SkypeConversation) {
+	names := conv.MemberNames()
+	events := conv.ThreadEvents()
+	memberships := conv.MembershipHistory()
+
+	fmt.Println()
+	color.New(color.FgCyan, color.Bold).Printf("=== %s ===\n", conv.GetConversationDisplayName())
+
+	if len(events) == 0 && len(memberships) == 0 {
+		color.New(color.FgYellow).Println("No membership or topic changes found")
+		return
+	}
+
+	if len(events) > 0 {
+		color.New(color.FgYellow).Println("\nTimeline")
+		for _, event := range events {
+			color.New(color.FgGreen).Printf("  %s  ", formatEventTime(event.Time))
+			fmt.Println(event.Describe(names))
+		}
+	}
+
+	if len(memberships) > 0 {
+		color.New(color.FgYellow).Println("\nMembers")
+		table := newSearchTable([]string{"Member", "Added", "Added By", "Removed", "Removed By"})
+		for _, membership := range memberships {
+			table.Append(membershipRow(membership, names))
+		}
+		table.Render()
+	}
+}
+
+// membershipRow formats a membership as a row of the members table
+func membershipRow(membership models.Membership, names map[string]string) []string {
+	name := func(id string) string {
+		if n, ok := names[id]; ok {
+			return n
+		}
+		return id
+	}
+
+	added, addedBy := "before the export", ""
+	if !membership.Added.IsZero() {
+		added, addedBy = formatEventTime(membership.Added), name(membership.AddedBy)
+	}
+	removed, removedBy := "", ""
+	if !membership.Removed.IsZero() {
+		removed, removedBy = formatEventTime(membership.Removed), name(membership.RemovedBy)
+	}
+	return []string{name(membership.Id), added, addedBy, removed, removedBy}
+}
+
+// formatEventTime formats the time of a group event, or "Unknown time"
+func formatEventTime(t time.Time) string {
+	if t.IsZero() {
+		return "Unknown time"
+	}
+	return t.Format("2006-01-02 15:04:05")
+}
+
+func init() {
+	rootCmd.AddCommand(membersHistoryCmd)
+}
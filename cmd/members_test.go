@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/beckxie/skype-history-viewer-cli/pkg/models"
+)
+
+func TestMembershipRow(t *testing.T) {
+	names := map[string]string{"8:alice": "Alice", "8:bob": "Bob"}
+	added := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		membership models.Membership
+		want       []string
+	}{
+		{
+			name:       "founder still a member",
+			membership: models.Membership{Id: "8:alice"},
+			want:       []string{"Alice", "before the export", "", "", ""},
+		},
+		{
+			name:       "added then removed",
+			membership: models.Membership{Id: "8:carol", Added: added, AddedBy: "8:alice", Removed: added.Add(time.Hour), RemovedBy: "8:bob"},
+			want:       []string{"8:carol", "2024-01-01 10:00:00", "Alice", "2024-01-01 11:00:00", "Bob"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := membershipRow(tt.membership, names); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("membershipRow() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
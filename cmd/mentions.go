@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/beckxie/skype-history-viewer-cli/pkg/models"
+	"github.com/beckxie/skype-history-viewer-cli/pkg/utils"
+	"github.com/beckxie/skype-history-viewer-cli/pkg/viewer"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var (
+	mentionsOutput string
+)
+
+// mentionsCmd represents the mentions command
+var mentionsCmd = &cobra.Command{
+	Use:   "mentions [user-id]",
+	Short: "List messages that mention you or another user",
+	Long: `List every message, across all conversations, that mentions the owner of the export or the given user
+with @name. Mentions of @all count as mentioning everyone.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Check if JSON path is provided
+		if err := checkJSONPath(); err != nil {
+			return err
+		}
+		if !slices.Contains(viewer.OutputFormats, mentionsOutput) {
+			return fmt.Errorf("invalid output format %q (expected one of %s)", mentionsOutput, strings.Join(viewer.OutputFormats, ", "))
+		}
+
+		userId := ""
+		if len(args) > 0 {
+			userId = args[0]
+		}
+		userId, results, err := findMentions(userId)
+		if err != nil {
+			return err
+		}
+
+		if mentionsOutput != viewer.OutputText {
+			return viewer.WriteSearchResults(os.Stdout, results, mentionsOutput)
+		}
+		displayMentions(userId, results)
+		return nil
+	},
+}
+
+// conversationSource reads conversations one at a time
+type conversationSource interface {
+	Next() (*models.SkypeConversation, error)
+	Close() error
+}
+
+// findMentions returns the messages mentioning userId, or the owner of the
+// export when userId is empty, along with whom they mention
+func findMentions(userId string) (string, []viewer.SearchResult, error) {
+	var source conversationSource
+	if idx := loadIndex(); idx != nil {
+		reader, err := idx.Reader(nil)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to load Skype history: %w", err)
+		}
+		source = reader
+		if userId == "" {
+			userId = idx.UserId
+		}
+	} else {
+		stream, err := utils.OpenHistoryStream(jsonPath, loadOptions())
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to load Skype history: %w", err)
+		}
+		source = stream
+		if userId == "" {
+			if err := stream.ReadHeader(); err != nil {
+				stream.Close()
+				return "", nil, fmt.Errorf("failed to load Skype history: %w", err)
+			}
+			userId = stream.UserId
+		}
+	}
+	defer source.Close()
+
+	if userId == "" {
+		return "", nil, fmt.Errorf("the export does not name its owner, give the user id to look for")
+	}
+
+	results := []viewer.SearchResult{}
+	for {
+		conv, err := source.Next()
+		if errors.Is(err, io.EOF) {
+			return userId, results, nil
+		}
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to read conversation: %w", err)
+		}
+		results = append(results, conversationMentions(conv, userId)...)
+	}
+}
+
+// conversationMentions returns the messages of conv mentioning userId
+func conversationMentions(conv *models.SkypeConversation, userId string) []viewer.SearchResult {
+	var results []viewer.SearchResult
+	for _, msg := range conv.MessageList {
+		if msg.MentionsUser(userId) {
+			results = append(results, viewer.SearchResult{
+				ConversationId:   conv.Id,
+				ConversationName: conv.GetConversationDisplayName(),
+				Message:          msg,
+				MatchType:        "mention",
+			})
+		}
+	}
+	return results
+}
+
+// displayMentions shows the messages mentioning userId
+func displayMentions(userId string, results []viewer.SearchResult) {
+	if len(results) == 0 {
+		color.New(color.FgYellow).Printf("No messages mention %s\n", userId)
+		return
+	}
+
+	fmt.Println()
+	color.New(color.FgCyan, color.Bold).Printf("=== Messages mentioning %s (%d) ===\n", userId, len(results))
+	fmt.Println(strings.Repeat("-", 80))
+
+	messageViewer := viewer.NewMessageViewer(viewer.ViewerOptions{})
+	for i, result := range results {
+		color.New(color.FgYellow).Printf("[%d] ", i+1)
+		color.New(color.FgMagenta).Printf("In: %s\n", result.ConversationName)
+		messageViewer.DisplayMessage(&result.Message)
+		fmt.Println(strings.Repeat("-", 80))
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(mentionsCmd)
+
+	// Local flags
+	mentionsCmd.Flags().StringVarP(&mentionsOutput, "output", "o", viewer.OutputText, "Output format: text, json, ndjson, csv or tsv")
+}
@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/beckxie/skype-history-viewer-cli/pkg/models"
+)
+
+func TestConversationMentions(t *testing.T) {
+	name := "Team"
+	conv := &models.SkypeConversation{
+		Id:          "19:team@thread.skype",
+		DisplayName: &name,
+		MessageList: []models.SkypeMessage{
+			{OriginalId: "1", Content: `<at id="8:live:me">Me</at> ping`},
+			{OriginalId: "2", Content: "no mention"},
+			{OriginalId: "3", Content: `<at id="8:live:bob">Bob</at> ping`},
+			{OriginalId: "4", Content: `<at id="*">all</at> standup`},
+		},
+	}
+
+	results := conversationMentions(conv, "live:me")
+	if len(results) != 2 || results[0].Message.OriginalId != "1" || results[1].Message.OriginalId != "4" {
+		t.Fatalf("unexpected results: %+v", results)
+	}
+	if results[0].ConversationName != "Team" || results[0].ConversationId != conv.Id || results[0].MatchType != "mention" {
+		t.Errorf("unexpected result: %+v", results[0])
+	}
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/beckxie/skype-history-viewer-cli/pkg/models"
+	"github.com/beckxie/skype-history-viewer-cli/pkg/utils"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var mergeOutputPath string
+
+// mergeCmd represents the merge command
+var mergeCmd = &cobra.Command{
+	Use:   "merge [export...]",
+	Short: "Merge several exports into one deduplicated history",
+	Long: `Merge exports taken at different times, or from different accounts sharing
+the same group chats, into a single export that every other command can read.
+
+Conversations are matched by id and keep the newest metadata; messages are
+deduplicated by id, keeping the most recent version.`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Load every export
+		histories := make([]*models.SkypeHistoryRoot, 0, len(args))
+		inputMessages := 0
+		options := loadOptions()
+		for _, exportPath := range args {
+			history, err := utils.LoadSkypeHistory(exportPath, options)
+			if err != nil {
+				return fmt.Errorf("failed to load %s: %w", exportPath, err)
+			}
+			for _, conv := range history.Conversations {
+				inputMessages += len(conv.MessageList)
+			}
+			histories = append(histories, history)
+		}
+
+		merged := models.MergeHistories(histories...)
+
+		// Ensure .json extension
+		if !strings.HasSuffix(mergeOutputPath, ".json") {
+			mergeOutputPath += ".json"
+		}
+
+		absPath, err := filepath.Abs(mergeOutputPath)
+		if err != nil {
+			return fmt.Errorf("invalid output path: %w", err)
+		}
+
+		size, err := utils.SaveSkypeHistory(merged, absPath)
+		if err != nil {
+			return fmt.Errorf("failed to save merged history: %w", err)
+		}
+
+		mergedMessages := 0
+		for _, conv := range merged.Conversations {
+			mergedMessages += len(conv.MessageList)
+		}
+
+		// Display success
+		color.New(color.FgGreen).Printf("✓ Merged %d exports into: %s\n", len(args), absPath)
+		fmt.Printf("  Conversations: %d\n", len(merged.Conversations))
+		fmt.Printf("  Messages: %d (%d duplicates removed)\n", mergedMessages, inputMessages-mergedMessages)
+		fmt.Printf("  Size: %.2f MB\n", float64(size)/(1024*1024))
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(mergeCmd)
+
+	// Local flags
+	mergeCmd.Flags().StringVarP(&mergeOutputPath, "output", "o", "merged_messages.json", "Output file path")
+}
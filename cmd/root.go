@@ -4,13 +4,18 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/beckxie/skype-history-viewer-cli/pkg/progress"
+	"github.com/beckxie/skype-history-viewer-cli/pkg/utils"
 	"github.com/spf13/cobra"
 )
 
 var (
 	// Global flags
-	jsonPath string
-	verbose  bool
+	jsonPath  string   // the export commands work on, the first -f
+	jsonPaths []string // every -f given, only search accepts several
+	verbose   bool
+	noIndex   bool
+	quiet     bool
 )
 
 // rootCmd represents the base command
@@ -25,6 +30,11 @@ var rootCmd = &cobra.Command{
 
 To use this tool, first export your Skype data from:
 https://support.microsoft.com/en-us/skype/how-do-i-export-or-delete-my-skype-data-84546e00-2fef-4c45-8ef6-3a27f83242cc`,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		if len(jsonPaths) > 0 {
+			jsonPath = jsonPaths[0]
+		}
+	},
 	Run: func(cmd *cobra.Command, args []string) {
 		// Show help if no subcommand is provided
 		cmd.Help()
@@ -38,8 +48,24 @@ func Execute() error {
 
 func init() {
 	// Global flags
-	rootCmd.PersistentFlags().StringVarP(&jsonPath, "file", "f", "", "Path to Skype export JSON file or directory")
+	rootCmd.PersistentFlags().StringArrayVarP(&jsonPaths, "file", "f", nil, "Path to Skype export JSON file, directory or .tar archive (search accepts several)")
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Enable verbose output")
+	rootCmd.PersistentFlags().BoolVar(&noIndex, "no-index", false, "Don't read or build the on-disk index")
+	rootCmd.PersistentFlags().BoolVar(&quiet, "quiet", false, "Don't report loading and search progress")
+}
+
+// progressReporter reports loading and search progress on stderr, keeping
+// stdout for results, unless --quiet is set
+func progressReporter() progress.Reporter {
+	if quiet {
+		return progress.Silent
+	}
+	return progress.NewTerminal(os.Stderr)
+}
+
+// loadOptions returns how exports are read by commands
+func loadOptions() utils.LoadOptions {
+	return utils.LoadOptions{Reporter: progressReporter()}
 }
 
 // Helper function to check if JSON path is provided
@@ -47,6 +73,9 @@ func checkJSONPath() error {
 	if jsonPath == "" {
 		return fmt.Errorf("please provide a JSON file path using -f or --file flag")
 	}
+	if len(jsonPaths) > 1 {
+		return fmt.Errorf("only search accepts several exports")
+	}
 
 	// Check if file exists
 	if _, err := os.Stat(jsonPath); os.IsNotExist(err) {
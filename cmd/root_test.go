@@ -5,6 +5,19 @@ import (
 	"testing"
 )
 
+func TestMain(m *testing.M) {
+	// Keep indexes built by command tests out of the real cache directory
+	cacheDir, err := os.MkdirTemp("", "skype-cli-cache")
+	if err != nil {
+		panic(err)
+	}
+	os.Setenv("XDG_CACHE_HOME", cacheDir)
+
+	code := m.Run()
+	os.RemoveAll(cacheDir)
+	os.Exit(code)
+}
+
 func TestCheckJSONPath(t *testing.T) {
 	// Reset jsonPath after test
 	defer func() { jsonPath = "" }()
@@ -0,0 +1,215 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/beckxie/skype-history-viewer-cli/pkg/search"
+	"github.com/fatih/color"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+// criteriaFlags are the search flags stored in a saved search
+var criteriaFlags = []string{
+	"query", "content", "sender", "case-sensitive", "regex", "advanced", "fuzzy", "max-distance",
+	"conversation", "limit", "date-from", "date-to", "context-width", "type", "has-attachment",
+	"has-link", "from", "mine", "others", "before", "after", "context", "sort",
+}
+
+// changedCriteriaFlag returns the first search criteria flag set on the
+// command line, or "" when there is none
+func changedCriteriaFlag(cmd *cobra.Command) string {
+	for _, name := range criteriaFlags {
+		if cmd.Flags().Changed(name) {
+			return name
+		}
+	}
+	return ""
+}
+
+// openSearchStore loads the saved searches and search history
+func openSearchStore() (*search.Store, error) {
+	storePath, err := search.StorePath()
+	if err != nil {
+		return nil, err
+	}
+	return search.LoadStore(storePath)
+}
+
+// recordSearch adds a search that ran on exportPaths to the history and
+// saves it when --save is set. Failing to keep the history only produces a warning.
+func recordSearch(store *search.Store, exportPaths []string, options search.SearchOptions) error {
+	fail := func(err error) error {
+		if saveSearchName != "" {
+			return fmt.Errorf("failed to save search: %w", err)
+		}
+		color.New(color.FgYellow).Fprintf(os.Stderr, "Warning: %v\n", err)
+		return nil
+	}
+
+	if store == nil {
+		var err error
+		if store, err = openSearchStore(); err != nil {
+			return fail(err)
+		}
+	}
+
+	exports := make([]string, len(exportPaths))
+	for i, exportPath := range exportPaths {
+		if absPath, err := filepath.Abs(exportPath); err == nil {
+			exportPath = absPath
+		}
+		exports[i] = exportPath
+	}
+	now := time.Now()
+	store.Record(options, strings.Join(exports, ", "), now)
+	if saveSearchName != "" {
+		store.SaveSearch(saveSearchName, options, now)
+	}
+	if err := store.Write(); err != nil {
+		return fail(err)
+	}
+
+	if saveSearchName != "" {
+		color.New(color.FgGreen).Fprintf(os.Stderr, "✓ Saved search %q, run it again with --run %s\n", saveSearchName, saveSearchName)
+	}
+	return nil
+}
+
+// displaySavedSearches shows saved searches in a table
+func displaySavedSearches(saved []search.SavedSearch) {
+	if len(saved) == 0 {
+		color.New(color.FgYellow).Println("No saved searches, save one with --save NAME")
+		return
+	}
+
+	table := newSearchTable([]string{"Name", "Search", "Saved"})
+	for _, s := range saved {
+		table.Append([]string{s.Name, describeSearch(s.Options), s.SavedAt.Local().Format("2006-01-02 15:04")})
+	}
+	table.Render()
+}
+
+// displaySearchHistory shows recently run searches in a table, most recent
+// first
+func displaySearchHistory(history []search.HistoryEntry) {
+	if len(history) == 0 {
+		color.New(color.FgYellow).Println("No searches run yet")
+		return
+	}
+
+	table := newSearchTable([]string{"#", "Search", "Export", "Ran"})
+	for i, entry := range history {
+		table.Append([]string{
+			fmt.Sprintf("%d", i+1),
+			describeSearch(entry.Options),
+			entry.Export,
+			entry.RanAt.Local().Format("2006-01-02 15:04"),
+		})
+	}
+	table.Render()
+}
+
+// newSearchTable creates a table in the style of the conversation list
+func newSearchTable(header []string) *tablewriter.Table {
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader(header)
+	table.SetBorder(true)
+	table.SetRowLine(true)
+	table.SetCenterSeparator("|")
+	table.SetColumnSeparator("|")
+	table.SetRowSeparator("-")
+	table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.SetAutoWrapText(false)
+	return table
+}
+
+// describeSearch writes options as the search flags producing them,
+// leaving out those at their default value
+func describeSearch(options search.SearchOptions) string {
+	parts := []string{"-q " + strconv.Quote(options.Query)}
+	add := func(flag string, value ...string) {
+		parts = append(parts, strings.Join(append([]string{"--" + flag}, value...), " "))
+	}
+
+	if !options.SearchInContent {
+		add("content=false")
+	}
+	if !options.SearchInSender {
+		add("sender=false")
+	}
+	if options.CaseSensitive {
+		add("case-sensitive")
+	}
+	if options.RegexSearch {
+		add("regex")
+	}
+	if options.AdvancedQuery {
+		add("advanced")
+	}
+	if options.Fuzzy {
+		add("fuzzy")
+	}
+	if options.MaxDistance > 0 {
+		add("max-distance", strconv.Itoa(options.MaxDistance))
+	}
+	if options.ConversationFilter != "" {
+		add("conversation", strconv.Quote(options.ConversationFilter))
+	}
+	if options.DateFrom != nil {
+		add("date-from", describeDate(*options.DateFrom))
+	}
+	if options.DateTo != nil {
+		add("date-to", describeDate(*options.DateTo))
+	}
+	for _, messageType := range options.MessageTypes {
+		add("type", messageType)
+	}
+	if options.HasAttachments {
+		add("has-attachment")
+	}
+	if options.HasUrlPreviews {
+		add("has-link")
+	}
+	for _, senderId := range options.SenderIds {
+		add("from", senderId)
+	}
+	if options.FromOwner != nil {
+		if *options.FromOwner {
+			add("mine")
+		} else {
+			add("others")
+		}
+	}
+	if options.ContextBefore > 0 {
+		add("before", strconv.Itoa(options.ContextBefore))
+	}
+	if options.ContextAfter > 0 {
+		add("after", strconv.Itoa(options.ContextAfter))
+	}
+	if options.ContextWidth > 0 && options.ContextWidth != search.DefaultContextWidth {
+		add("context-width", strconv.Itoa(options.ContextWidth))
+	}
+	if options.Limit != defaultSearchLimit {
+		add("limit", strconv.Itoa(options.Limit))
+	}
+	if options.Sort != "" && options.Sort != search.SortRelevance {
+		add("sort", options.Sort)
+	}
+	return strings.Join(parts, " ")
+}
+
+// describeDate formats t the way --date-from and --date-to accept it,
+// keeping the time of day only when there is one
+func describeDate(t time.Time) string {
+	if t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0 {
+		return t.Format("2006-01-02")
+	}
+	return strconv.Quote(t.Format("2006-01-02 15:04:05"))
+}
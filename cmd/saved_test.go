@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/beckxie/skype-history-viewer-cli/pkg/search"
+)
+
+func TestDescribeSearch(t *testing.T) {
+	from := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 3, 4, 18, 30, 0, 0, time.UTC)
+	others := false
+
+	tests := []struct {
+		options search.SearchOptions
+		want    string
+	}{
+		{
+			search.SearchOptions{Query: "hello", SearchInContent: true, SearchInSender: true, Limit: defaultSearchLimit, Sort: search.SortRelevance, ContextWidth: search.DefaultContextWidth},
+			`-q "hello"`,
+		},
+		{
+			search.SearchOptions{
+				Query:              `say "hi"`,
+				SearchInContent:    true,
+				AdvancedQuery:      true,
+				ConversationFilter: "Team",
+				DateFrom:           &from,
+				DateTo:             &to,
+				MessageTypes:       []string{"RichText", "Event/Call"},
+				FromOwner:          &others,
+				Limit:              0,
+				Sort:               search.SortDate,
+			},
+			`-q "say \"hi\"" --sender=false --advanced --conversation "Team" --date-from 2024-01-02 --date-to "2024-03-04 18:30:00" --type RichText --type Event/Call --others --limit 0 --sort date`,
+		},
+	}
+
+	for _, tt := range tests {
+		if got := describeSearch(tt.options); got != tt.want {
+			t.Errorf("describeSearch() = %s, want %s", got, tt.want)
+		}
+	}
+}
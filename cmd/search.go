@@ -1,85 +1,144 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"time"
 
+	"github.com/beckxie/skype-history-viewer-cli/pkg/analyzer"
+	"github.com/beckxie/skype-history-viewer-cli/pkg/index"
 	"github.com/beckxie/skype-history-viewer-cli/pkg/search"
 	"github.com/beckxie/skype-history-viewer-cli/pkg/utils"
 	"github.com/beckxie/skype-history-viewer-cli/pkg/viewer"
+	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 )
 
+// defaultSearchLimit is the number of results shown when --limit is not set
+const defaultSearchLimit = 50
+
 var (
 	searchQuery        string
 	searchInContent    bool
 	searchInSender     bool
 	caseSensitive      bool
+	regexSearch        bool
+	advancedQuery      bool
+	fuzzySearch        bool
+	maxDistance        int
 	conversationFilter string
 	searchLimit        int
 	searchDateFrom     string
 	searchDateTo       string
+	searchSort         string
+	contextWidth       int
+	searchWorkers      int
+	contextBefore      int
+	contextAfter       int
+	contextLines       int
+	searchOutput       string
+	messageTypes       []string
+	hasAttachments     bool
+	hasLinks           bool
+	senderIds          []string
+	ownMessages        bool
+	othersMessages     bool
+	persistCache       bool
+	saveSearchName     string
+	runSearchName      string
+	listSaved          bool
+	showHistory        bool
 )
 
 // searchCmd represents the search command
 var searchCmd = &cobra.Command{
 	Use:   "search",
 	Short: "Search through messages",
-	Long:  `Search for specific text in your Skype chat history with various filters and options.`,
+	Long: `Search for specific text in your Skype chat history with various filters and options.
+
+With --advanced the query is parsed as a boolean expression:
+  "exact phrase"            match a phrase
+  a AND b, a b              both terms (AND is implied between terms)
+  a OR b                    either term
+  NOT a, -a                 exclude a term
+  ( ... )                   group expressions
+  from:alice                sender name or id contains "alice"
+  in:project                conversation name contains "project"
+  type:call                 message type contains "call"
+  mentions:alice            mentions someone whose name or id contains "alice"
+  mentions:me               mentions the owner of the export (or @all)
+  before:2024-01-01         sent before a date
+  after:2024-01-01          sent on or after a date
+  has:attachment, has:link  messages with attachments or links`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		// Check if JSON path is provided
-		if err := checkJSONPath(); err != nil {
-			return err
+		if listSaved || showHistory {
+			store, err := openSearchStore()
+			if err != nil {
+				return err
+			}
+			if listSaved {
+				displaySavedSearches(store.Saved)
+			} else {
+				displaySearchHistory(store.History)
+			}
+			return nil
 		}
 
-		// Check if search query is provided
-		if searchQuery == "" {
-			return fmt.Errorf("please provide a search query using -q or --query flag")
+		// Check which exports to search
+		exportPaths, err := searchExportPaths()
+		if err != nil {
+			return err
 		}
 
-		// Load Skype history
-		history, err := utils.LoadSkypeHistory(jsonPath)
-		if err != nil {
-			return fmt.Errorf("failed to load Skype history: %w", err)
+		if !slices.Contains(viewer.OutputFormats, searchOutput) {
+			return fmt.Errorf("invalid output format %q (expected one of %s)", searchOutput, strings.Join(viewer.OutputFormats, ", "))
 		}
 
-		// Parse date filters
-		var dateFromTime, dateToTime *time.Time
-		if searchDateFrom != "" {
-			t, err := utils.ParseDateString(searchDateFrom)
-			if err != nil {
-				return fmt.Errorf("invalid date-from: %w", err)
+		// A saved search brings its own criteria, flags only change how it runs
+		var store *search.Store
+		var searchOptions search.SearchOptions
+		if runSearchName != "" {
+			if flag := changedCriteriaFlag(cmd); flag != "" {
+				return fmt.Errorf("--%s can't be combined with --run", flag)
 			}
-			dateFromTime = t
-		}
-		if searchDateTo != "" {
-			t, err := utils.ParseDateString(searchDateTo)
-			if err != nil {
-				return fmt.Errorf("invalid date-to: %w", err)
+			var err error
+			if store, err = openSearchStore(); err != nil {
+				return err
+			}
+			saved, ok := store.Find(runSearchName)
+			if !ok {
+				return fmt.Errorf("no saved search named %q, see --list-saved", runSearchName)
+			}
+			searchOptions = saved.Options
+		} else {
+			var err error
+			if searchOptions, err = searchOptionsFromFlags(cmd); err != nil {
+				return err
 			}
-			dateToTime = t
 		}
-
-		// Create search manager
-		searchManager := search.NewSearchManager(history)
-
-		// Prepare search options
-		searchOptions := search.SearchOptions{
-			Query:              searchQuery,
-			SearchInContent:    searchInContent,
-			SearchInSender:     searchInSender,
-			CaseSensitive:      caseSensitive,
-			ConversationFilter: conversationFilter,
-			DateFrom:           dateFromTime,
-			DateTo:             dateToTime,
-			Limit:              searchLimit,
+		searchOptions.Workers = searchWorkers
+		searchOptions.Reporter = progressReporter()
+		if err := search.ValidateOptions(searchOptions); err != nil {
+			return err
 		}
 
-		// Perform search
-		results, err := searchManager.Search(cmd.Context(), searchOptions)
+		results, err := searchExports(cmd.Context(), exportPaths, searchOptions)
 		if err != nil && err != cmd.Context().Err() {
 			return fmt.Errorf("search failed: %w", err)
 		}
+		if err == nil {
+			if err := recordSearch(store, exportPaths, searchOptions); err != nil {
+				return err
+			}
+		}
+
+		if searchOutput != viewer.OutputText {
+			return viewer.WriteSearchResults(os.Stdout, results, searchOutput)
+		}
 
 		// Create viewer and display results
 		viewerOptions := viewer.ViewerOptions{
@@ -92,19 +151,287 @@ var searchCmd = &cobra.Command{
 	},
 }
 
+// searchOptionsFromFlags builds the search described by the command line
+func searchOptionsFromFlags(cmd *cobra.Command) (search.SearchOptions, error) {
+	// Check if search query is provided
+	if searchQuery == "" {
+		return search.SearchOptions{}, fmt.Errorf("please provide a search query using -q or --query flag")
+	}
+
+	// Parse date filters
+	var dateFromTime, dateToTime *time.Time
+	if searchDateFrom != "" {
+		t, err := utils.ParseDateString(searchDateFrom)
+		if err != nil {
+			return search.SearchOptions{}, fmt.Errorf("invalid date-from: %w", err)
+		}
+		dateFromTime = t
+	}
+	if searchDateTo != "" {
+		t, err := utils.ParseDateString(searchDateTo)
+		if err != nil {
+			return search.SearchOptions{}, fmt.Errorf("invalid date-to: %w", err)
+		}
+		dateToTime = t
+	}
+
+	if searchSort != search.SortRelevance && searchSort != search.SortDate {
+		return search.SearchOptions{}, fmt.Errorf("invalid sort order %q (expected %s or %s)", searchSort, search.SortRelevance, search.SortDate)
+	}
+
+	// Explicit -B/-A take precedence over -C
+	if !cmd.Flags().Changed("before") {
+		contextBefore = contextLines
+	}
+	if !cmd.Flags().Changed("after") {
+		contextAfter = contextLines
+	}
+	if contextBefore < 0 || contextAfter < 0 {
+		return search.SearchOptions{}, fmt.Errorf("context message counts cannot be negative")
+	}
+
+	if ownMessages && othersMessages {
+		return search.SearchOptions{}, fmt.Errorf("--mine and --others can't be combined")
+	}
+	var fromOwner *bool
+	if ownMessages || othersMessages {
+		fromOwner = &ownMessages
+	}
+
+	// Prepare search options
+	searchOptions := search.SearchOptions{
+		Query:              searchQuery,
+		SearchInContent:    searchInContent,
+		SearchInSender:     searchInSender,
+		CaseSensitive:      caseSensitive,
+		RegexSearch:        regexSearch,
+		AdvancedQuery:      advancedQuery,
+		Fuzzy:              fuzzySearch,
+		MaxDistance:        maxDistance,
+		ConversationFilter: conversationFilter,
+		DateFrom:           dateFromTime,
+		DateTo:             dateToTime,
+		Limit:              searchLimit,
+		Sort:               searchSort,
+		ContextWidth:       contextWidth,
+		ContextBefore:      contextBefore,
+		ContextAfter:       contextAfter,
+		MessageTypes:       messageTypes,
+		HasAttachments:     hasAttachments,
+		HasUrlPreviews:     hasLinks,
+		SenderIds:          senderIds,
+		FromOwner:          fromOwner,
+	}
+	return searchOptions, nil
+}
+
+// searchExportPaths returns the exports to search: every -f given, with
+// directories of exports replaced by the exports in them
+func searchExportPaths() ([]string, error) {
+	paths := jsonPaths
+	if len(paths) == 0 && jsonPath != "" {
+		paths = []string{jsonPath}
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("please provide a JSON file path using -f or --file flag")
+	}
+	for _, path := range paths {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return nil, fmt.Errorf("file or directory not found: %s", path)
+		}
+	}
+	return utils.ExpandExports(paths)
+}
+
+// searchExports runs the same search on every export, merging the results
+// of several exports. An interrupted search returns what was found so far.
+func searchExports(ctx context.Context, exportPaths []string, options search.SearchOptions) ([]viewer.SearchResult, error) {
+	labels := exportLabels(exportPaths)
+	sets := make([]search.ExportResults, 0, len(exportPaths))
+	for i, exportPath := range exportPaths {
+		// Cached results belong to a single export
+		searchManager := search.NewSearchManager(nil)
+		var saveCache func()
+		if persistCache {
+			saveCache = loadSearchCache(searchManager, exportPath)
+		}
+
+		results, err := runSearch(ctx, searchManager, exportPath, options)
+		if err == nil && saveCache != nil {
+			saveCache()
+		}
+		if len(exportPaths) == 1 {
+			return results, err
+		}
+
+		sets = append(sets, search.ExportResults{Label: labels[i], Results: results})
+		if err == ctx.Err() && err != nil {
+			return search.MergeResults(sets, options), err
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", exportPath, err)
+		}
+	}
+	return search.MergeResults(sets, options), nil
+}
+
+// exportLabels names exports after their file or directory, falling back
+// to the full path when two would share a name
+func exportLabels(exportPaths []string) []string {
+	labels := make([]string, len(exportPaths))
+	counts := make(map[string]int)
+	for i, exportPath := range exportPaths {
+		name := filepath.Base(exportPath)
+		if strings.EqualFold(name, "messages.json") {
+			name = filepath.Base(filepath.Dir(exportPath))
+		} else {
+			name = strings.TrimSuffix(name, filepath.Ext(name))
+		}
+		labels[i] = name
+		counts[name]++
+	}
+
+	for i := range labels {
+		if counts[labels[i]] > 1 {
+			labels[i] = exportPaths[i]
+		}
+	}
+	return labels
+}
+
+// runSearch picks the fastest way to answer a search of an export: the
+// full-text index for plain queries, otherwise reading conversations
+// through the index when possible, skipping those the filters rule out, or
+// streaming the export
+func runSearch(ctx context.Context, searchManager *search.SearchManager, exportPath string, options search.SearchOptions) ([]viewer.SearchResult, error) {
+	idx := loadIndexAt(exportPath)
+	if idx != nil {
+		options.OwnerId = idx.UserId
+	}
+	if idx != nil && search.IndexableQuery(options) {
+		segment, err := idx.OpenText(exportPath)
+		if err == nil {
+			defer segment.Close()
+			if verbose {
+				fmt.Fprintf(os.Stderr, "Using full-text index (%d messages, %d terms)\n", segment.DocCount(), segment.TermCount())
+			}
+			return searchManager.SearchIndex(ctx, idx, segment, options)
+		}
+		if verbose {
+			fmt.Fprintf(os.Stderr, "Full-text index unavailable: %v\n", err)
+		}
+	}
+
+	var reader search.ConversationReader
+	if idx != nil {
+		indexReader, err := idx.Reader(indexFilter(options))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load Skype history: %w", err)
+		}
+		defer indexReader.Close()
+		reader = indexReader
+	} else {
+		// Reading the export is what the search progress measures, so only
+		// announce it here
+		stream, err := utils.OpenHistoryStream(exportPath, utils.LoadOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to load Skype history: %w", err)
+		}
+		defer stream.Close()
+		options.Reporter.LoadStarted(exportPath, stream.Size())
+		reader = stream
+	}
+
+	return searchManager.SearchStream(ctx, reader, options)
+}
+
+// loadSearchCache fills the manager with the results persisted for an
+// export and returns a function saving them back. Cache problems only
+// produce warnings.
+func loadSearchCache(searchManager *search.SearchManager, exportPath string) func() {
+	warn := func(err error) {
+		color.New(color.FgYellow).Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+
+	cachePath, err := index.CachePath(exportPath)
+	if err != nil {
+		warn(err)
+		return nil
+	}
+	exportKey, err := index.ExportKey(exportPath)
+	if err != nil {
+		warn(err)
+		return nil
+	}
+	if err := searchManager.LoadCache(cachePath, exportKey); err != nil {
+		warn(err)
+	}
+
+	return func() {
+		if err := searchManager.SaveCache(cachePath, exportKey); err != nil {
+			warn(err)
+		}
+	}
+}
+
+// indexFilter rules out conversations using indexed metadata alone
+func indexFilter(options search.SearchOptions) func(entry *index.ConversationEntry) bool {
+	return func(entry *index.ConversationEntry) bool {
+		if options.ConversationFilter != "" && !analyzer.Contains(entry.DisplayName, options.ConversationFilter) {
+			return false
+		}
+		if options.DateFrom != nil && !entry.LastMessage.IsZero() && entry.LastMessage.Before(*options.DateFrom) {
+			return false
+		}
+		if options.DateTo != nil && !entry.FirstMessage.IsZero() && entry.FirstMessage.After(*options.DateTo) {
+			return false
+		}
+		if len(options.MessageTypes) > 0 && !slices.ContainsFunc(options.MessageTypes, func(t string) bool { return entry.MessageTypes[t] > 0 }) {
+			return false
+		}
+		if len(options.SenderIds) > 0 && !slices.ContainsFunc(options.SenderIds, func(id string) bool { return slices.Contains(entry.Senders, id) }) {
+			return false
+		}
+		return true
+	}
+}
+
 func init() {
 	rootCmd.AddCommand(searchCmd)
 
 	// Required flags
-	searchCmd.Flags().StringVarP(&searchQuery, "query", "q", "", "Search query text (required)")
-	searchCmd.MarkFlagRequired("query")
+	searchCmd.Flags().StringVarP(&searchQuery, "query", "q", "", "Search query text (required unless --run is used)")
 
 	// Optional flags
 	searchCmd.Flags().BoolVar(&searchInContent, "content", true, "Search in message content")
 	searchCmd.Flags().BoolVar(&searchInSender, "sender", true, "Search in sender names")
 	searchCmd.Flags().BoolVar(&caseSensitive, "case-sensitive", false, "Case-sensitive search")
+	searchCmd.Flags().BoolVar(&regexSearch, "regex", false, "Treat the query as a regular expression")
+	searchCmd.Flags().BoolVar(&advancedQuery, "advanced", false, "Parse the query with the boolean query language (AND/OR/NOT, phrases, field qualifiers)")
+	searchCmd.Flags().BoolVar(&fuzzySearch, "fuzzy", false, "Match words within a few typos (Damerau-Levenshtein distance)")
+	searchCmd.Flags().IntVar(&maxDistance, "max-distance", 0, "Edits allowed per word in fuzzy mode (0 picks by word length: none up to 2 characters, 1 up to 5, 2 beyond)")
 	searchCmd.Flags().StringVar(&conversationFilter, "conversation", "", "Filter by conversation name")
-	searchCmd.Flags().IntVar(&searchLimit, "limit", 50, "Maximum number of results (0 for unlimited)")
+	searchCmd.Flags().IntVar(&searchLimit, "limit", defaultSearchLimit, "Maximum number of results (0 for unlimited)")
 	searchCmd.Flags().StringVar(&searchDateFrom, "date-from", "", "Search from this date (YYYY-MM-DD)")
 	searchCmd.Flags().StringVar(&searchDateTo, "date-to", "", "Search to this date (YYYY-MM-DD)")
+	searchCmd.Flags().IntVar(&contextWidth, "context-width", search.DefaultContextWidth, "Characters of context shown around each match")
+	searchCmd.Flags().StringSliceVar(&messageTypes, "type", nil, "Only messages of these exact types (e.g. RichText/Media_GenericFile,Event/Call)")
+	searchCmd.Flags().BoolVar(&hasAttachments, "has-attachment", false, "Only messages with attachments")
+	searchCmd.Flags().BoolVar(&hasLinks, "has-link", false, "Only messages with link previews")
+	searchCmd.Flags().StringSliceVar(&senderIds, "from", nil, "Only messages from these exact sender ids (e.g. 8:live:alice)")
+	searchCmd.Flags().BoolVar(&ownMessages, "mine", false, "Only messages sent by the export owner")
+	searchCmd.Flags().BoolVar(&othersMessages, "others", false, "Only messages not sent by the export owner")
+	searchCmd.Flags().IntVarP(&contextBefore, "before", "B", 0, "Show N messages before each match")
+	searchCmd.Flags().IntVarP(&contextAfter, "after", "A", 0, "Show N messages after each match")
+	searchCmd.Flags().IntVarP(&contextLines, "context", "C", 0, "Show N messages before and after each match")
+	searchCmd.Flags().IntVar(&searchWorkers, "workers", 0, "Number of parallel searchers (0 for one per CPU)")
+	searchCmd.Flags().BoolVar(&persistCache, "cache", false, "Reuse results of identical searches across runs until the export changes")
+	searchCmd.Flags().StringVarP(&searchOutput, "output", "o", viewer.OutputText, "Output format: text, json, ndjson, csv or tsv")
+	searchCmd.Flags().StringVar(&searchSort, "sort", search.SortRelevance, "Order results by relevance (needs the index, export order without it) or date")
+
+	// Saved searches
+	searchCmd.Flags().StringVar(&saveSearchName, "save", "", "Save this search under a name to run it again with --run")
+	searchCmd.Flags().StringVar(&runSearchName, "run", "", "Run the search saved under a name")
+	searchCmd.Flags().BoolVar(&listSaved, "list-saved", false, "List saved searches")
+	searchCmd.Flags().BoolVar(&showHistory, "history", false, "List recently run searches")
 }
@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExportLabels(t *testing.T) {
+	tests := []struct {
+		paths []string
+		want  []string
+	}{
+		{
+			[]string{"exports/alice/messages.json", "exports/bob", "8_live_carol_export.tar", "dave.json"},
+			[]string{"alice", "bob", "8_live_carol_export", "dave"},
+		},
+		{
+			[]string{"2023/alice", "2024/alice", "bob"},
+			[]string{"2023/alice", "2024/alice", "bob"},
+		},
+	}
+
+	for _, tt := range tests {
+		if got := exportLabels(tt.paths); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("exportLabels(%v) = %v, want %v", tt.paths, got, tt.want)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package cmd
 import (
 	"fmt"
 
+	"github.com/beckxie/skype-history-viewer-cli/pkg/models"
 	"github.com/beckxie/skype-history-viewer-cli/pkg/utils"
 	"github.com/spf13/cobra"
 )
@@ -18,15 +19,28 @@ var statsCmd = &cobra.Command{
 			return err
 		}
 
-		// Load Skype history
-		history, err := utils.LoadSkypeHistory(jsonPath)
+		// Statistics come straight from the index, which is built on first use
+		if !noIndex {
+			idx, err := loadOrBuildIndex()
+			if err != nil {
+				return fmt.Errorf("failed to load Skype history: %w", err)
+			}
+			utils.DisplayStats(idx.Stats())
+			return nil
+		}
+
+		// Stream Skype history and generate statistics
+		collector := utils.NewStatsCollector()
+		err := utils.StreamSkypeHistory(jsonPath, loadOptions(), func(conv *models.SkypeConversation) error {
+			collector.Add(conv)
+			return nil
+		})
 		if err != nil {
 			return fmt.Errorf("failed to load Skype history: %w", err)
 		}
 
-		// Generate and display statistics
-		stats := utils.GetStats(history)
-		utils.DisplayStats(stats)
+		// Display statistics
+		utils.DisplayStats(collector.Stats())
 
 		return nil
 	},
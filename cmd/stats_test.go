@@ -46,7 +46,7 @@ func TestStatsCmd(t *testing.T) {
 
 	t.Run("Stats with valid file", func(t *testing.T) {
 		// We need to reset flags for each test because they are global in root.go
-		jsonPath = ""
+		jsonPath, jsonPaths = "", nil
 
 		// Execute
 		// We can't easily reset PersistentFlags on the global rootCmd without side effects,
@@ -77,7 +77,7 @@ func TestStatsCmd(t *testing.T) {
 	})
 
 	t.Run("Stats without file", func(t *testing.T) {
-		jsonPath = ""
+		jsonPath, jsonPaths = "", nil
 		_, err := executeCommand(rootCmd, "stats")
 		if err == nil {
 			t.Error("expected error when file is missing")
@@ -37,7 +37,7 @@ var viewCmd = &cobra.Command{
 		}
 
 		// Load Skype history
-		history, err := utils.LoadSkypeHistory(jsonPath)
+		catalog, err := openConversationCatalog()
 		if err != nil {
 			return fmt.Errorf("failed to load Skype history: %w", err)
 		}
@@ -53,17 +53,20 @@ var viewCmd = &cobra.Command{
 
 		// Interactive mode if no conversation specified
 		if conversationNum == 0 {
-			return interactiveView(history)
+			return interactiveView(catalog)
 		}
 
 		// Validate conversation number
-		if conversationNum < 1 || conversationNum > len(history.Conversations) {
+		if conversationNum < 1 || conversationNum > catalog.Len() {
 			return fmt.Errorf("invalid conversation number: %d (valid range: 1-%d)",
-				conversationNum, len(history.Conversations))
+				conversationNum, catalog.Len())
 		}
 
 		// Get selected conversation
-		conv := &history.Conversations[conversationNum-1]
+		conv, err := catalog.Conversation(conversationNum)
+		if err != nil {
+			return fmt.Errorf("failed to load conversation: %w", err)
+		}
 
 		// Parse date filters
 		var dateFromTime, dateToTime *time.Time
@@ -98,7 +101,7 @@ var viewCmd = &cobra.Command{
 }
 
 // interactiveView provides an interactive conversation selection
-func interactiveView(history *models.SkypeHistoryRoot) error {
+func interactiveView(catalog *conversationCatalog) error {
 	// Create viewer for listing
 	viewerOptions := viewer.ViewerOptions{
 		ShowSystemMessages: showSystem,
@@ -106,7 +109,7 @@ func interactiveView(history *models.SkypeHistoryRoot) error {
 	messageViewer := viewer.NewMessageViewer(viewerOptions)
 
 	// Display conversation list
-	messageViewer.DisplayConversationList(history.Conversations)
+	messageViewer.DisplayConversationSummaries(catalog.Summaries)
 
 	// Prompt for selection
 	reader := bufio.NewReader(os.Stdin)
@@ -123,12 +126,11 @@ func interactiveView(history *models.SkypeHistoryRoot) error {
 		return fmt.Errorf("invalid input: %s", input)
 	}
 
-	if num < 1 || num > len(history.Conversations) {
-		return fmt.Errorf("invalid conversation number: %d", num)
-	}
-
 	// Get selected conversation
-	conv := &history.Conversations[num-1]
+	conv, err := catalog.Conversation(num)
+	if err != nil {
+		return err
+	}
 
 	// View conversation
 	viewerOptions.PageSize = pageSize
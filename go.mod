@@ -7,6 +7,7 @@ require (
 	github.com/olekukonko/tablewriter v0.0.5
 	github.com/spf13/cobra v1.8.0
 	golang.org/x/term v0.29.0
+	golang.org/x/text v0.22.0
 )
 
 require (
@@ -0,0 +1,186 @@
+// Package analyzer normalizes and tokenizes message text for searching.
+// Text is folded with Unicode NFKC, which also maps full-width and
+// half-width variants to their usual form, and optionally lowercased.
+// Words in scripts written without spaces (Han, Hiragana, Katakana and
+// Hangul) are split into overlapping bigrams.
+package analyzer
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Normalized is folded text that remembers where each of its bytes came
+// from in the original text
+type Normalized struct {
+	Text   string
+	starts []int // original start of the segment each byte came from
+	ends   []int // original end of the segment each byte came from
+}
+
+// Normalize folds text with NFKC, lowercasing it as well when foldCase is
+// set
+func Normalize(text string, foldCase bool) *Normalized {
+	if isASCII(text) {
+		// NFKC leaves ASCII untouched and lowercasing keeps byte positions
+		if foldCase {
+			text = strings.ToLower(text)
+		}
+		return &Normalized{Text: text}
+	}
+
+	var b strings.Builder
+	n := &Normalized{
+		starts: make([]int, 0, len(text)),
+		ends:   make([]int, 0, len(text)),
+	}
+
+	var it norm.Iter
+	it.InitString(norm.NFKC, text)
+	for !it.Done() {
+		start := it.Pos()
+		segment := string(it.Next())
+		end := it.Pos()
+		if foldCase {
+			segment = strings.ToLower(segment)
+		}
+
+		b.WriteString(segment)
+		for range len(segment) {
+			n.starts = append(n.starts, start)
+			n.ends = append(n.ends, end)
+		}
+	}
+
+	n.Text = b.String()
+	return n
+}
+
+// Span maps the byte span [start, end) of the normalized text back to the
+// original text
+func (n *Normalized) Span(start, end int) (int, int) {
+	if n.starts == nil {
+		return start, end
+	}
+	if start >= end {
+		if start >= len(n.starts) {
+			return n.originalLen(), n.originalLen()
+		}
+		return n.starts[start], n.starts[start]
+	}
+	return n.starts[start], n.ends[end-1]
+}
+
+func (n *Normalized) originalLen() int {
+	if len(n.ends) == 0 {
+		return 0
+	}
+	return n.ends[len(n.ends)-1]
+}
+
+// Fold returns the normalized form of text, for comparing against
+// Normalize output
+func Fold(text string, foldCase bool) string {
+	return Normalize(text, foldCase).Text
+}
+
+// Contains reports whether needle occurs in text once both are folded,
+// ignoring case
+func Contains(text, needle string) bool {
+	return strings.Contains(Fold(text, true), Fold(needle, true))
+}
+
+// Token is a term and the byte span it was taken from in the original text
+type Token struct {
+	Term  string
+	Start int
+	End   int
+}
+
+// Tokenize splits text into lowercased, normalized terms. Runs of letters
+// and digits form one term, while runs of CJK characters are split into
+// overlapping bigrams, or kept whole when only one character long.
+func Tokenize(text string) []Token {
+	n := Normalize(text, true)
+	var tokens []Token
+
+	emit := func(start, end int) {
+		origStart, origEnd := n.Span(start, end)
+		tokens = append(tokens, Token{Term: n.Text[start:end], Start: origStart, End: origEnd})
+	}
+
+	for i := 0; i < len(n.Text); {
+		r, size := utf8.DecodeRuneInString(n.Text[i:])
+		switch {
+		case isCJK(r):
+			// Collect the rune boundaries of the CJK run
+			bounds := []int{i}
+			for i < len(n.Text) {
+				r, size := utf8.DecodeRuneInString(n.Text[i:])
+				if !isCJK(r) {
+					break
+				}
+				i += size
+				bounds = append(bounds, i)
+			}
+			if len(bounds) == 2 {
+				emit(bounds[0], bounds[1])
+				continue
+			}
+			for j := 0; j+2 < len(bounds); j++ {
+				emit(bounds[j], bounds[j+2])
+			}
+		case isWordRune(r):
+			start := i
+			for i < len(n.Text) {
+				r, size := utf8.DecodeRuneInString(n.Text[i:])
+				if !isWordRune(r) || isCJK(r) {
+					break
+				}
+				i += size
+			}
+			emit(start, i)
+		default:
+			i += size
+		}
+	}
+
+	return tokens
+}
+
+// Terms returns just the terms of Tokenize
+func Terms(text string) []string {
+	tokens := Tokenize(text)
+	terms := make([]string, len(tokens))
+	for i, token := range tokens {
+		terms[i] = token.Term
+	}
+	return terms
+}
+
+// isCJK reports whether r belongs to a script written without spaces
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) ||
+		unicode.Is(unicode.Hiragana, r) ||
+		unicode.Is(unicode.Katakana, r) ||
+		unicode.Is(unicode.Hangul, r) ||
+		r == 'ー' // prolonged sound mark, common to both kana
+}
+
+// isWordRune reports whether r is part of a word, including combining
+// marks so that decomposed accents don't split words
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r)
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
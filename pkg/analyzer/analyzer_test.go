@@ -0,0 +1,92 @@
+package analyzer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		text     string
+		foldCase bool
+		want     string
+	}{
+		{"Hello", true, "hello"},
+		{"Hello", false, "Hello"},
+		{"ｍｅｅｔｉｎｇ", true, "meeting"},
+		{"ＭＥＥＴＩＮＧ", false, "MEETING"},
+		{"ｶﾀｶﾅ", true, "カタカナ"},
+		{"é", true, "é"},
+		{"①", true, "1"},
+	}
+
+	for _, tt := range tests {
+		if got := Normalize(tt.text, tt.foldCase).Text; got != tt.want {
+			t.Errorf("Normalize(%q, %v) = %q, want %q", tt.text, tt.foldCase, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizedSpan(t *testing.T) {
+	text := "say ｍｅｅｔｉｎｇ now"
+	n := Normalize(text, true)
+
+	start := len("say ")
+	end := start + len("meeting")
+	origStart, origEnd := n.Span(start, end)
+	if got := text[origStart:origEnd]; got != "ｍｅｅｔｉｎｇ" {
+		t.Errorf("Span mapped to %q", got)
+	}
+
+	// ASCII text maps onto itself
+	ascii := Normalize("Plain Text", true)
+	if s, e := ascii.Span(6, 10); s != 6 || e != 10 {
+		t.Errorf("expected identity span, got %d-%d", s, e)
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"Hello, World!", []string{"hello", "world"}},
+		{"release-notes_v2.pdf", []string{"release", "notes", "v2", "pdf"}},
+		{"Café ÜBER", []string{"café", "über"}},
+		{"會議室", []string{"會議", "議室"}},
+		{"好", []string{"好"}},
+		{"明天的meeting改到會議室", []string{"明天", "天的", "meeting", "改到", "到會", "會議", "議室"}},
+		{"ミーティング", []string{"ミー", "ーテ", "ティ", "ィン", "ング"}},
+		{"ｍｅｅｔｉｎｇ", []string{"meeting"}},
+		{"회의실", []string{"회의", "의실"}},
+		{"  ", nil},
+	}
+
+	for _, tt := range tests {
+		got := Terms(tt.text)
+		if len(got) == 0 && len(tt.want) == 0 {
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Terms(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestTokenSpans(t *testing.T) {
+	text := "開ｍｔｇ"
+	for _, token := range Tokenize(text) {
+		if token.Term == "mtg" && text[token.Start:token.End] != "ｍｔｇ" {
+			t.Errorf("token span points at %q", text[token.Start:token.End])
+		}
+	}
+}
+
+func TestContains(t *testing.T) {
+	if !Contains("週會 Ｔｅａｍ", "team") {
+		t.Error("expected width and case folded match")
+	}
+	if Contains("Team", "teams") {
+		t.Error("unexpected match")
+	}
+}
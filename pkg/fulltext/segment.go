@@ -0,0 +1,440 @@
+// Package fulltext implements an inverted index over message text with
+// BM25 ranking, using the terms produced by the analyzer package. A segment
+// is written once by a Builder and then queried from disk: the term
+// dictionary is loaded into memory while postings lists are read on demand.
+//
+// Segment layout, all integers are unsigned varints:
+//
+//	magic "SHVFTS02"
+//	key length, key bytes
+//	document count, then per document: conversation, message, length
+//	term count, then per term in sorted order: length, bytes, df, postings size
+//	postings of every term in dictionary order, each a list of
+//	(document delta, term frequency) pairs
+package fulltext
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/beckxie/skype-history-viewer-cli/pkg/analyzer"
+)
+
+const magic = "SHVFTS02"
+
+// senderPrefix marks terms taken from sender names. The analyzer never
+// produces it, so sender terms can't collide with content terms.
+const senderPrefix = "@"
+
+// BM25 parameters
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// ErrCorrupt is returned when a segment can't be decoded
+var ErrCorrupt = errors.New("corrupt full-text segment")
+
+// DocRef locates an indexed message within an export
+type DocRef struct {
+	Conversation int
+	Message      int
+}
+
+// termPostings accumulates the encoded postings of one term
+type termPostings struct {
+	data    []byte
+	df      int
+	lastDoc int
+}
+
+// Builder collects documents for a new segment. Documents must be added in
+// the order they should be numbered.
+type Builder struct {
+	docs    []DocRef
+	lengths []int
+	terms   map[string]*termPostings
+}
+
+// NewBuilder creates an empty segment builder
+func NewBuilder() *Builder {
+	return &Builder{terms: make(map[string]*termPostings)}
+}
+
+// Add indexes a message's content and sender name
+func (b *Builder) Add(ref DocRef, content, sender string) {
+	doc := len(b.docs)
+
+	frequencies := make(map[string]int)
+	length := 0
+	for _, term := range analyzer.Terms(content) {
+		frequencies[term]++
+		length++
+	}
+	for _, term := range analyzer.Terms(sender) {
+		frequencies[senderPrefix+term]++
+		length++
+	}
+
+	b.docs = append(b.docs, ref)
+	b.lengths = append(b.lengths, length)
+
+	for term, tf := range frequencies {
+		postings, ok := b.terms[term]
+		if !ok {
+			postings = &termPostings{}
+			b.terms[term] = postings
+		}
+		postings.data = binary.AppendUvarint(postings.data, uint64(doc-postings.lastDoc))
+		postings.data = binary.AppendUvarint(postings.data, uint64(tf))
+		postings.df++
+		postings.lastDoc = doc
+	}
+}
+
+// DocCount returns the number of documents added so far
+func (b *Builder) DocCount() int {
+	return len(b.docs)
+}
+
+// WriteTo writes the segment, tagged with key, to w
+func (b *Builder) WriteTo(w io.Writer, key string) error {
+	bw := bufio.NewWriter(w)
+	var buf []byte
+
+	buf = append(buf, magic...)
+	buf = appendString(buf, key)
+
+	buf = binary.AppendUvarint(buf, uint64(len(b.docs)))
+	for i, ref := range b.docs {
+		buf = binary.AppendUvarint(buf, uint64(ref.Conversation))
+		buf = binary.AppendUvarint(buf, uint64(ref.Message))
+		buf = binary.AppendUvarint(buf, uint64(b.lengths[i]))
+	}
+
+	terms := make([]string, 0, len(b.terms))
+	for term := range b.terms {
+		terms = append(terms, term)
+	}
+	sort.Strings(terms)
+
+	buf = binary.AppendUvarint(buf, uint64(len(terms)))
+	for _, term := range terms {
+		postings := b.terms[term]
+		buf = appendString(buf, term)
+		buf = binary.AppendUvarint(buf, uint64(postings.df))
+		buf = binary.AppendUvarint(buf, uint64(len(postings.data)))
+	}
+	if _, err := bw.Write(buf); err != nil {
+		return fmt.Errorf("failed to write segment: %w", err)
+	}
+
+	for _, term := range terms {
+		if _, err := bw.Write(b.terms[term].data); err != nil {
+			return fmt.Errorf("failed to write segment: %w", err)
+		}
+	}
+
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("failed to write segment: %w", err)
+	}
+	return nil
+}
+
+func appendString(buf []byte, s string) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(s)))
+	return append(buf, s...)
+}
+
+// dictionaryEntry is a term of a loaded segment
+type dictionaryEntry struct {
+	term   string
+	df     int
+	offset int64 // relative to the start of the postings section
+	size   int
+}
+
+// Segment is a segment opened for querying
+type Segment struct {
+	file         *os.File
+	key          string
+	docs         []DocRef
+	lengths      []int
+	avgLength    float64
+	dictionary   []dictionaryEntry
+	postingsBase int64
+}
+
+// Open loads the document table and dictionary of a segment file
+func Open(path string) (*Segment, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	segment, err := readSegment(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return segment, nil
+}
+
+func readSegment(file *os.File) (*Segment, error) {
+	r := &countingReader{r: bufio.NewReader(file)}
+
+	header := make([]byte, len(magic))
+	if _, err := io.ReadFull(r, header); err != nil || string(header) != magic {
+		return nil, ErrCorrupt
+	}
+
+	s := &Segment{file: file}
+	var err error
+	if s.key, err = r.readString(); err != nil {
+		return nil, err
+	}
+
+	docCount, err := r.readInt()
+	if err != nil {
+		return nil, err
+	}
+	s.docs = make([]DocRef, docCount)
+	s.lengths = make([]int, docCount)
+	totalLength := 0
+	for i := range s.docs {
+		if s.docs[i].Conversation, err = r.readInt(); err != nil {
+			return nil, err
+		}
+		if s.docs[i].Message, err = r.readInt(); err != nil {
+			return nil, err
+		}
+		if s.lengths[i], err = r.readInt(); err != nil {
+			return nil, err
+		}
+		totalLength += s.lengths[i]
+	}
+	if docCount > 0 {
+		s.avgLength = float64(totalLength) / float64(docCount)
+	}
+
+	termCount, err := r.readInt()
+	if err != nil {
+		return nil, err
+	}
+	s.dictionary = make([]dictionaryEntry, termCount)
+	var offset int64
+	for i := range s.dictionary {
+		entry := &s.dictionary[i]
+		if entry.term, err = r.readString(); err != nil {
+			return nil, err
+		}
+		if entry.df, err = r.readInt(); err != nil {
+			return nil, err
+		}
+		if entry.size, err = r.readInt(); err != nil {
+			return nil, err
+		}
+		entry.offset = offset
+		offset += int64(entry.size)
+	}
+	s.postingsBase = r.n
+
+	return s, nil
+}
+
+// Key returns the key the segment was written with
+func (s *Segment) Key() string {
+	return s.key
+}
+
+// DocCount returns the number of indexed documents
+func (s *Segment) DocCount() int {
+	return len(s.docs)
+}
+
+// TermCount returns the number of distinct terms
+func (s *Segment) TermCount() int {
+	return len(s.dictionary)
+}
+
+// Close releases the segment file
+func (s *Segment) Close() error {
+	return s.file.Close()
+}
+
+// Query describes a full-text lookup. Every term has to occur, as part of
+// a longer word or not, in the content or the sender name as enabled.
+type Query struct {
+	Terms   []string
+	Content bool
+	Sender  bool
+}
+
+// Hit is a document matching a query
+type Hit struct {
+	Doc   DocRef
+	Score float64
+}
+
+// Search returns the documents containing every query term, sorted by
+// descending BM25 score and then by document order. Query terms are
+// expanded to every indexed word containing them, with partial matches
+// weighted by how much of the word they cover.
+func (s *Segment) Search(query Query) ([]Hit, error) {
+	if len(query.Terms) == 0 {
+		return nil, nil
+	}
+
+	var scores map[int]float64
+	for _, queryTerm := range query.Terms {
+		termScores, err := s.scoreTerm(queryTerm, query)
+		if err != nil {
+			return nil, err
+		}
+
+		if scores == nil {
+			scores = termScores
+			continue
+		}
+		// Every query term is required
+		for doc, score := range scores {
+			if termScore, ok := termScores[doc]; ok {
+				scores[doc] = score + termScore
+			} else {
+				delete(scores, doc)
+			}
+		}
+		if len(scores) == 0 {
+			break
+		}
+	}
+
+	hits := make([]Hit, 0, len(scores))
+	docs := make([]int, 0, len(scores))
+	for doc := range scores {
+		docs = append(docs, doc)
+	}
+	sort.Ints(docs)
+	for _, doc := range docs {
+		hits = append(hits, Hit{Doc: s.docs[doc], Score: scores[doc]})
+	}
+	sort.SliceStable(hits, func(i, j int) bool {
+		return hits[i].Score > hits[j].Score
+	})
+
+	return hits, nil
+}
+
+// scoreTerm scores the documents containing any expansion of queryTerm.
+// The expansions are treated as a single term whose frequency in a
+// document is the weighted sum of theirs, so a rare word merely containing
+// the query term can't outrank exact matches.
+func (s *Segment) scoreTerm(queryTerm string, query Query) (map[int]float64, error) {
+	frequencies := make(map[int]float64)
+
+	for i := range s.dictionary {
+		entry := &s.dictionary[i]
+		word, isSender := strings.CutPrefix(entry.term, senderPrefix)
+		if isSender && !query.Sender || !isSender && !query.Content {
+			continue
+		}
+		if !strings.Contains(word, queryTerm) {
+			continue
+		}
+
+		weight := float64(len(queryTerm)) / float64(len(word))
+		err := s.readPostings(entry, func(doc, tf int) {
+			frequencies[doc] += weight * float64(tf)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	n := float64(len(s.docs))
+	df := float64(len(frequencies))
+	idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+
+	scores := make(map[int]float64, len(frequencies))
+	for doc, tf := range frequencies {
+		norm := 1 - bm25B + bm25B*float64(s.lengths[doc])/s.avgLength
+		scores[doc] = idf * tf * (bm25K1 + 1) / (tf + bm25K1*norm)
+	}
+	return scores, nil
+}
+
+// readPostings decodes the postings list of a dictionary entry
+func (s *Segment) readPostings(entry *dictionaryEntry, fn func(doc, tf int)) error {
+	data := make([]byte, entry.size)
+	if _, err := s.file.ReadAt(data, s.postingsBase+entry.offset); err != nil {
+		return fmt.Errorf("failed to read postings: %w", err)
+	}
+
+	doc := 0
+	for i := 0; i < entry.df; i++ {
+		delta, n := binary.Uvarint(data)
+		if n <= 0 {
+			return ErrCorrupt
+		}
+		data = data[n:]
+		tf, n := binary.Uvarint(data)
+		if n <= 0 {
+			return ErrCorrupt
+		}
+		data = data[n:]
+
+		doc += int(delta)
+		if doc >= len(s.docs) {
+			return ErrCorrupt
+		}
+		fn(doc, int(tf))
+	}
+	return nil
+}
+
+// countingReader tracks how many bytes have been consumed
+type countingReader struct {
+	r *bufio.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func (c *countingReader) ReadByte() (byte, error) {
+	b, err := c.r.ReadByte()
+	if err == nil {
+		c.n++
+	}
+	return b, err
+}
+
+func (c *countingReader) readInt() (int, error) {
+	v, err := binary.ReadUvarint(c)
+	if err != nil || v > math.MaxInt32 {
+		return 0, ErrCorrupt
+	}
+	return int(v), nil
+}
+
+func (c *countingReader) readString() (string, error) {
+	length, err := c.readInt()
+	if err != nil {
+		return "", err
+	}
+	buf := make([]byte, length)
+	if _, err := io.ReadFull(c, buf); err != nil {
+		return "", ErrCorrupt
+	}
+	return string(buf), nil
+}
@@ -0,0 +1,94 @@
+package fulltext
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeSegment(t *testing.T, b *Builder, key string) *Segment {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.fts")
+
+	var buf bytes.Buffer
+	if err := b.WriteTo(&buf, key); err != nil {
+		t.Fatalf("WriteTo error = %v", err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	segment, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open error = %v", err)
+	}
+	t.Cleanup(func() { segment.Close() })
+	return segment
+}
+
+func TestSegmentSearch(t *testing.T) {
+	b := NewBuilder()
+	b.Add(DocRef{0, 0}, "the deploy went fine", "Alice")
+	b.Add(DocRef{0, 1}, "deploy deploy deploy again", "Bob")
+	b.Add(DocRef{0, 3}, "lunch?", "Alice")
+	b.Add(DocRef{2, 0}, "redeployment scheduled after lunch", "Carol")
+
+	segment := writeSegment(t, b, "key-1")
+	if segment.Key() != "key-1" || segment.DocCount() != 4 {
+		t.Fatalf("unexpected segment: key=%q docs=%d", segment.Key(), segment.DocCount())
+	}
+
+	t.Run("ranked by relevance", func(t *testing.T) {
+		hits, err := segment.Search(Query{Terms: []string{"deploy"}, Content: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var docs []DocRef
+		for _, hit := range hits {
+			docs = append(docs, hit.Doc)
+		}
+		// Repeated term first, the partial match in "redeployment" last
+		want := []DocRef{{0, 1}, {0, 0}, {2, 0}}
+		if !reflect.DeepEqual(docs, want) {
+			t.Errorf("expected %v, got %v", want, docs)
+		}
+	})
+
+	t.Run("every term required", func(t *testing.T) {
+		hits, _ := segment.Search(Query{Terms: []string{"deploy", "lunch"}, Content: true})
+		if len(hits) != 1 || hits[0].Doc != (DocRef{2, 0}) {
+			t.Errorf("expected only the redeployment message, got %v", hits)
+		}
+	})
+
+	t.Run("sender field", func(t *testing.T) {
+		hits, _ := segment.Search(Query{Terms: []string{"alice"}, Sender: true})
+		if len(hits) != 2 {
+			t.Errorf("expected 2 sender hits, got %v", hits)
+		}
+		hits, _ = segment.Search(Query{Terms: []string{"alice"}, Content: true})
+		if len(hits) != 0 {
+			t.Errorf("sender terms must not match content, got %v", hits)
+		}
+	})
+
+	t.Run("no match", func(t *testing.T) {
+		hits, _ := segment.Search(Query{Terms: []string{"missing"}, Content: true, Sender: true})
+		if len(hits) != 0 {
+			t.Errorf("expected no hits, got %v", hits)
+		}
+	})
+}
+
+func TestOpenCorruptSegment(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bad.fts")
+	if err := os.WriteFile(path, []byte("not a segment"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(path); !errors.Is(err, ErrCorrupt) {
+		t.Errorf("expected ErrCorrupt, got %v", err)
+	}
+}
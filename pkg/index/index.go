@@ -0,0 +1,545 @@
+package index
+
+import (
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/beckxie/skype-history-viewer-cli/pkg/fulltext"
+	"github.com/beckxie/skype-history-viewer-cli/pkg/models"
+	"github.com/beckxie/skype-history-viewer-cli/pkg/utils"
+)
+
+// formatVersion is bumped whenever the on-disk layout or the text indexed
+// from messages changes; the latter also calls for a new search cache version
+const formatVersion = 6
+
+// fingerprintSampleSize is how much of the head and tail of the export is
+// hashed; hashing the whole file would defeat the purpose of the index
+const fingerprintSampleSize = 64 * 1024
+
+var (
+	// ErrNotFound is returned when no index has been built for an export
+	ErrNotFound = errors.New("index not found")
+	// ErrStale is returned when the export changed since the index was built
+	ErrStale = errors.New("index is out of date")
+)
+
+// Fingerprint identifies the exact export an index was built from
+type Fingerprint struct {
+	Size    int64
+	ModTime int64
+	Hash    string
+}
+
+// Index holds conversation metadata, message offsets and pre-parsed
+// timestamps of an export so that commands can skip re-parsing it
+type Index struct {
+	Version       int
+	Fingerprint   Fingerprint
+	SourceFile    string // file holding messages.json
+	DataOffset    int64  // start of messages.json within SourceFile
+	DataSize      int64
+	BuiltAt       time.Time
+	UserId        string
+	ExportDate    string
+	Conversations []ConversationEntry
+
+	text *fulltext.Builder // full-text segment of a freshly built index
+}
+
+// ConversationEntry is the indexed form of a conversation
+type ConversationEntry struct {
+	models.ConversationSummary
+	Offset       int64
+	FirstMessage time.Time // zero when no message has a valid timestamp
+	Senders      []string
+	MessageTypes map[string]int
+	Messages     []MessageEntry
+}
+
+// MessageEntry is the indexed form of a message
+type MessageEntry struct {
+	Offset    int64
+	Timestamp int64 // Unix nanoseconds, 0 when the timestamp is invalid
+	System    bool
+}
+
+// Time returns the pre-parsed message timestamp
+func (m MessageEntry) Time() (time.Time, bool) {
+	if m.Timestamp == 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(0, m.Timestamp).UTC(), true
+}
+
+// Path returns where the index of an export is stored
+func Path(exportPath string) (string, error) {
+	absPath, err := filepath.Abs(exportPath)
+	if err != nil {
+		return "", fmt.Errorf("invalid export path: %w", err)
+	}
+
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate cache directory: %w", err)
+	}
+
+	sum := sha256.Sum256([]byte(absPath))
+	name := hex.EncodeToString(sum[:8]) + ".idx"
+	return filepath.Join(cacheDir, "skype-history-viewer-cli", "index", name), nil
+}
+
+// TextPath returns where the full-text segment of an export is stored,
+// next to its index
+func TextPath(exportPath string) (string, error) {
+	indexPath, err := Path(exportPath)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(indexPath, ".idx") + ".fts", nil
+}
+
+// CachePath returns where persisted search results of an export are
+// stored, next to its index
+func CachePath(exportPath string) (string, error) {
+	indexPath, err := Path(exportPath)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(indexPath, ".idx") + ".cache", nil
+}
+
+// ExportKey identifies the current content of an export, so that data
+// derived from it can tell when it changed
+func ExportKey(exportPath string) (string, error) {
+	sourceFile, err := utils.ResolveExportFile(exportPath)
+	if err != nil {
+		return "", err
+	}
+	fingerprint, err := ComputeFingerprint(sourceFile)
+	if err != nil {
+		return "", err
+	}
+	return textKey(fingerprint), nil
+}
+
+// ComputeFingerprint fingerprints the file holding messages.json using
+// its size, modification time and a hash of its head and tail
+func ComputeFingerprint(sourceFile string) (Fingerprint, error) {
+	file, err := os.Open(sourceFile)
+	if err != nil {
+		return Fingerprint{}, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return Fingerprint{}, fmt.Errorf("failed to get file info: %w", err)
+	}
+
+	hash := sha256.New()
+	if _, err := io.CopyN(hash, file, fingerprintSampleSize); err != nil && err != io.EOF {
+		return Fingerprint{}, fmt.Errorf("failed to hash file: %w", err)
+	}
+	if info.Size() > 2*fingerprintSampleSize {
+		if _, err := file.Seek(-fingerprintSampleSize, io.SeekEnd); err != nil {
+			return Fingerprint{}, fmt.Errorf("failed to hash file: %w", err)
+		}
+		if _, err := io.Copy(hash, file); err != nil {
+			return Fingerprint{}, fmt.Errorf("failed to hash file: %w", err)
+		}
+	}
+
+	return Fingerprint{
+		Size:    info.Size(),
+		ModTime: info.ModTime().UnixNano(),
+		Hash:    hex.EncodeToString(hash.Sum(nil)),
+	}, nil
+}
+
+// Build parses the export once and collects everything the index stores
+func Build(exportPath string, options utils.LoadOptions) (*Index, error) {
+	stream, err := utils.OpenHistoryStream(exportPath, options)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
+
+	sourceFile, err := filepath.Abs(stream.SourceFile())
+	if err != nil {
+		return nil, fmt.Errorf("invalid export path: %w", err)
+	}
+	fingerprint, err := ComputeFingerprint(sourceFile)
+	if err != nil {
+		return nil, err
+	}
+
+	idx := &Index{
+		Version:     formatVersion,
+		Fingerprint: fingerprint,
+		SourceFile:  sourceFile,
+		DataOffset:  stream.DataOffset(),
+		DataSize:    stream.Size(),
+		BuiltAt:     time.Now(),
+		text:        fulltext.NewBuilder(),
+	}
+
+	for {
+		conv, err := stream.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		convIndex := len(idx.Conversations)
+		idx.Conversations = append(idx.Conversations, newConversationEntry(conv, stream.Offsets()))
+
+		// System messages are never searched, leave them out of the text index
+		for i := range conv.MessageList {
+			msg := &conv.MessageList[i]
+			if msg.IsSystemMessage() {
+				continue
+			}
+			ref := fulltext.DocRef{Conversation: convIndex, Message: i}
+			idx.text.Add(ref, msg.GetDisplayText(), msg.GetSenderDisplayName())
+		}
+	}
+
+	idx.UserId = stream.UserId
+	idx.ExportDate = stream.ExportDate
+	return idx, nil
+}
+
+// newConversationEntry indexes a decoded conversation
+func newConversationEntry(conv *models.SkypeConversation, offsets utils.ConversationOffsets) ConversationEntry {
+	entry := ConversationEntry{
+		ConversationSummary: conv.Summary(),
+		Offset:              offsets.Conversation,
+		MessageTypes:        make(map[string]int),
+		Messages:            make([]MessageEntry, len(conv.MessageList)),
+	}
+
+	senders := make(map[string]bool)
+	for i := range conv.MessageList {
+		msg := &conv.MessageList[i]
+		if !senders[msg.From] {
+			senders[msg.From] = true
+			entry.Senders = append(entry.Senders, msg.From)
+		}
+		entry.MessageTypes[msg.MessageType]++
+
+		messageEntry := MessageEntry{
+			Offset: offsets.Messages[i],
+			System: msg.IsSystemMessage(),
+		}
+		if t, err := msg.GetTimestamp(); err == nil {
+			messageEntry.Timestamp = t.UnixNano()
+			if entry.FirstMessage.IsZero() || t.Before(entry.FirstMessage) {
+				entry.FirstMessage = t
+			}
+		}
+		entry.Messages[i] = messageEntry
+	}
+
+	return entry
+}
+
+// Load reads the index of an export, returning ErrNotFound when none was
+// built and ErrStale when the export changed since
+func Load(exportPath string) (*Index, error) {
+	indexPath, err := Path(exportPath)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(indexPath)
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open index: %w", err)
+	}
+	defer file.Close()
+
+	var idx Index
+	if err := gob.NewDecoder(file).Decode(&idx); err != nil || idx.Version != formatVersion {
+		// Unreadable or outdated layout, treat as needing a rebuild
+		return nil, ErrStale
+	}
+
+	sourceFile, err := utils.ResolveExportFile(exportPath)
+	if err != nil {
+		return nil, err
+	}
+	fingerprint, err := ComputeFingerprint(sourceFile)
+	if err != nil {
+		return nil, err
+	}
+	if fingerprint != idx.Fingerprint {
+		return nil, ErrStale
+	}
+
+	return &idx, nil
+}
+
+// Save writes the index, and the full-text segment of a freshly built
+// index, to their location in the user cache directory
+func (idx *Index) Save(exportPath string) error {
+	indexPath, err := Path(exportPath)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(indexPath), 0755); err != nil {
+		return fmt.Errorf("failed to create index directory: %w", err)
+	}
+
+	// The segment goes first so that a fresh index never points to an old one
+	if idx.text != nil {
+		textPath, err := TextPath(exportPath)
+		if err != nil {
+			return err
+		}
+		err = writeFileAtomic(textPath, func(w io.Writer) error {
+			return idx.text.WriteTo(w, textKey(idx.Fingerprint))
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return writeFileAtomic(indexPath, func(w io.Writer) error {
+		return gob.NewEncoder(w).Encode(idx)
+	})
+}
+
+// writeFileAtomic writes to a temporary file first so that readers never
+// see a partial file
+func writeFileAtomic(path string, write func(w io.Writer) error) error {
+	tmpFile, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed to create index: %w", err)
+	}
+	defer os.Remove(tmpFile.Name())
+
+	if err := write(tmpFile); err != nil {
+		tmpFile.Close()
+		return fmt.Errorf("failed to write index: %w", err)
+	}
+	if err := tmpFile.Close(); err != nil {
+		return fmt.Errorf("failed to write index: %w", err)
+	}
+
+	if err := os.Rename(tmpFile.Name(), path); err != nil {
+		return fmt.Errorf("failed to write index: %w", err)
+	}
+	return nil
+}
+
+// textKey ties a full-text segment to the export it was built from
+func textKey(fp Fingerprint) string {
+	return fmt.Sprintf("%d:%d:%s", fp.Size, fp.ModTime, fp.Hash)
+}
+
+// OpenText opens the full-text segment built along with the index,
+// returning ErrNotFound when there is none and ErrStale when it belongs to
+// another build of the export
+func (idx *Index) OpenText(exportPath string) (*fulltext.Segment, error) {
+	textPath, err := TextPath(exportPath)
+	if err != nil {
+		return nil, err
+	}
+
+	segment, err := fulltext.Open(textPath)
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	if errors.Is(err, fulltext.ErrCorrupt) {
+		return nil, ErrStale
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open text index: %w", err)
+	}
+
+	if segment.Key() != textKey(idx.Fingerprint) {
+		segment.Close()
+		return nil, ErrStale
+	}
+	return segment, nil
+}
+
+// Remove deletes the stored index, full-text segment and search cache of an
+// export, if any
+func Remove(exportPath string) error {
+	indexPath, err := Path(exportPath)
+	if err != nil {
+		return err
+	}
+	textPath, err := TextPath(exportPath)
+	if err != nil {
+		return err
+	}
+	cachePath, err := CachePath(exportPath)
+	if err != nil {
+		return err
+	}
+
+	for _, path := range []string{indexPath, textPath, cachePath} {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove index: %w", err)
+		}
+	}
+	return nil
+}
+
+// MessageCount returns the number of indexed messages
+func (idx *Index) MessageCount() int {
+	total := 0
+	for i := range idx.Conversations {
+		total += idx.Conversations[i].MessageCount
+	}
+	return total
+}
+
+// Summaries returns the conversation summaries in export order
+func (idx *Index) Summaries() []models.ConversationSummary {
+	summaries := make([]models.ConversationSummary, len(idx.Conversations))
+	for i := range idx.Conversations {
+		summaries[i] = idx.Conversations[i].ConversationSummary
+	}
+	return summaries
+}
+
+// Stats returns the same statistics as utils.GetStats without touching the
+// export
+func (idx *Index) Stats() map[string]interface{} {
+	stats := make(map[string]interface{})
+	stats["total_conversations"] = len(idx.Conversations)
+
+	totalMessages := 0
+	totalUsers := make(map[string]bool)
+	messageTypes := make(map[string]int)
+	var firstMessageTime, lastMessageTime time.Time
+
+	for i := range idx.Conversations {
+		entry := &idx.Conversations[i]
+		totalMessages += entry.MessageCount
+		for _, sender := range entry.Senders {
+			totalUsers[sender] = true
+		}
+		for msgType, count := range entry.MessageTypes {
+			messageTypes[msgType] += count
+		}
+		if !entry.FirstMessage.IsZero() && (firstMessageTime.IsZero() || entry.FirstMessage.Before(firstMessageTime)) {
+			firstMessageTime = entry.FirstMessage
+		}
+		if entry.LastMessage.After(lastMessageTime) {
+			lastMessageTime = entry.LastMessage
+		}
+	}
+
+	stats["total_messages"] = totalMessages
+	stats["total_users"] = len(totalUsers)
+	stats["message_types"] = messageTypes
+
+	// Date range
+	if !firstMessageTime.IsZero() {
+		stats["first_message_date"] = firstMessageTime.Format("2006-01-02")
+	}
+	if !lastMessageTime.IsZero() {
+		stats["last_message_date"] = lastMessageTime.Format("2006-01-02")
+	}
+
+	return stats
+}
+
+// ReadConversation decodes a single conversation (0-based) straight from
+// its offset in the export
+func (idx *Index) ReadConversation(i int) (*models.SkypeConversation, error) {
+	if i < 0 || i >= len(idx.Conversations) {
+		return nil, fmt.Errorf("conversation %d out of range", i+1)
+	}
+
+	file, err := utils.OpenExportFile(idx.SourceFile, idx.DataOffset)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return file.ReadConversation(idx.Conversations[i].Offset)
+}
+
+// Reader returns a reader over the conversations accepted by keep, which
+// is called with the conversation entries in export order. A nil keep
+// accepts every conversation.
+func (idx *Index) Reader(keep func(entry *ConversationEntry) bool) (*Reader, error) {
+	file, err := utils.OpenExportFile(idx.SourceFile, idx.DataOffset)
+	if err != nil {
+		return nil, err
+	}
+	return &Reader{idx: idx, file: file, keep: keep}, nil
+}
+
+// Reader reads indexed conversations one at a time, seeking directly to
+// each accepted conversation
+type Reader struct {
+	idx  *Index
+	file *utils.ExportFile
+	keep func(entry *ConversationEntry) bool
+	next int
+}
+
+// Next returns the next accepted conversation, or io.EOF at the end
+func (r *Reader) Next() (*models.SkypeConversation, error) {
+	for r.next < len(r.idx.Conversations) {
+		entry := &r.idx.Conversations[r.next]
+		r.next++
+		if r.keep != nil && !r.keep(entry) {
+			continue
+		}
+		return r.file.ReadConversation(entry.Offset)
+	}
+	return nil, io.EOF
+}
+
+// BytesRead returns the offset reached within messages.json
+func (r *Reader) BytesRead() int64 {
+	if r.next < len(r.idx.Conversations) {
+		return r.idx.Conversations[r.next].Offset
+	}
+	return r.idx.DataSize
+}
+
+// Size returns the size of messages.json
+func (r *Reader) Size() int64 {
+	return r.idx.DataSize
+}
+
+// Owner returns the id of the owner of the indexed export
+func (r *Reader) Owner() (string, error) {
+	return r.idx.UserId, nil
+}
+
+// Close releases the underlying file
+func (r *Reader) Close() error {
+	return r.file.Close()
+}
+
+// FormatFingerprint renders a fingerprint for display
+func FormatFingerprint(fp Fingerprint) string {
+	return strings.Join([]string{
+		fmt.Sprintf("size=%d", fp.Size),
+		"mtime=" + time.Unix(0, fp.ModTime).Format(time.RFC3339),
+		"sha256=" + fp.Hash[:16],
+	}, " ")
+}
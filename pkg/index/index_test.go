@@ -0,0 +1,287 @@
+package index
+
+import (
+	"archive/tar"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/beckxie/skype-history-viewer-cli/pkg/utils"
+)
+
+const testExport = `{
+  "userId": "index-user",
+  "exportDate": "2024-01-10T00:00:00Z",
+  "conversations": [
+    {
+      "id": "c1",
+      "displayName": "General",
+      "MessageList": [
+        {"id": "m1", "from": "alice", "content": "Hello", "messagetype": "Text", "originalarrivaltime": "2024-01-01T10:00:00Z"},
+        {"id": "m2", "from": "bob", "content": "Hi", "messagetype": "RichText", "originalarrivaltime": "2024-01-01T11:00:00Z"}
+      ]
+    } ,
+    {
+      "id": "c2",
+      "displayName": "Later",
+      "MessageList": [
+        {"id": "m3", "from": "alice", "content": "Bye", "messagetype": "Control/ThreadActivity", "originalarrivaltime": "2024-02-01T10:00:00Z"}
+      ]
+    }
+  ]
+}`
+
+func writeExport(t *testing.T) string {
+	t.Helper()
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	exportPath := filepath.Join(t.TempDir(), "messages.json")
+	if err := os.WriteFile(exportPath, []byte(testExport), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return exportPath
+}
+
+func TestBuild(t *testing.T) {
+	exportPath := writeExport(t)
+
+	idx, err := Build(exportPath, utils.LoadOptions{})
+	if err != nil {
+		t.Fatalf("Build error = %v", err)
+	}
+
+	if idx.UserId != "index-user" || len(idx.Conversations) != 2 || idx.MessageCount() != 3 {
+		t.Fatalf("unexpected index: user=%s conversations=%d messages=%d",
+			idx.UserId, len(idx.Conversations), idx.MessageCount())
+	}
+
+	first := idx.Conversations[0]
+	if first.DisplayName != "General" || first.ParticipantCount != 2 {
+		t.Errorf("unexpected summary: %+v", first.ConversationSummary)
+	}
+	if !first.FirstMessage.Equal(time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected first message time: %v", first.FirstMessage)
+	}
+	if got, ok := first.Messages[1].Time(); !ok || !got.Equal(time.Date(2024, 1, 1, 11, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected pre-parsed timestamp: %v", got)
+	}
+	if !idx.Conversations[1].Messages[0].System {
+		t.Error("expected ThreadActivity message to be flagged as system")
+	}
+
+	stats := idx.Stats()
+	if stats["total_users"] != 2 || stats["first_message_date"] != "2024-01-01" || stats["last_message_date"] != "2024-02-01" {
+		t.Errorf("unexpected stats: %v", stats)
+	}
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	exportPath := writeExport(t)
+
+	if _, err := Load(exportPath); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound before building, got %v", err)
+	}
+
+	idx, err := Build(exportPath, utils.LoadOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := idx.Save(exportPath); err != nil {
+		t.Fatalf("Save error = %v", err)
+	}
+
+	loaded, err := Load(exportPath)
+	if err != nil {
+		t.Fatalf("Load error = %v", err)
+	}
+	if len(loaded.Conversations) != 2 || loaded.Conversations[1].DisplayName != "Later" {
+		t.Errorf("loaded index does not match built index")
+	}
+
+	// Touching the export must invalidate the index
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(exportPath, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(exportPath); !errors.Is(err, ErrStale) {
+		t.Errorf("expected ErrStale after export changed, got %v", err)
+	}
+
+	if err := Remove(exportPath); err != nil {
+		t.Fatalf("Remove error = %v", err)
+	}
+	if _, err := Load(exportPath); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound after removal, got %v", err)
+	}
+}
+
+func TestReadConversation(t *testing.T) {
+	exportPath := writeExport(t)
+	idx, err := Build(exportPath, utils.LoadOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, wantId := range []string{"c1", "c2"} {
+		conv, err := idx.ReadConversation(i)
+		if err != nil {
+			t.Fatalf("ReadConversation(%d) error = %v", i, err)
+		}
+		if conv.Id != wantId {
+			t.Errorf("ReadConversation(%d) = %s, want %s", i, conv.Id, wantId)
+		}
+	}
+
+	if _, err := idx.ReadConversation(2); err == nil {
+		t.Error("expected error for out of range conversation")
+	}
+}
+
+func TestReader(t *testing.T) {
+	exportPath := writeExport(t)
+	idx, err := Build(exportPath, utils.LoadOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reader, err := idx.Reader(func(entry *ConversationEntry) bool {
+		return entry.DisplayName == "Later"
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+
+	conv, err := reader.Next()
+	if err != nil {
+		t.Fatalf("Next error = %v", err)
+	}
+	if conv.Id != "c2" || len(conv.MessageList) != 1 {
+		t.Errorf("unexpected conversation: %s", conv.Id)
+	}
+	if _, err := reader.Next(); err != io.EOF {
+		t.Errorf("expected io.EOF, got %v", err)
+	}
+}
+
+func TestBuildFromArchive(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	archivePath := filepath.Join(t.TempDir(), "export.tar")
+	file, err := os.Create(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tw := tar.NewWriter(file)
+	for _, entry := range []struct{ name, content string }{
+		{"media/photo.jpg", "not really a jpeg"},
+		{"messages.json", testExport},
+	} {
+		tw.WriteHeader(&tar.Header{Name: entry.name, Mode: 0644, Size: int64(len(entry.content)), Typeflag: tar.TypeReg})
+		tw.Write([]byte(entry.content))
+	}
+	tw.Close()
+	file.Close()
+
+	idx, err := Build(archivePath, utils.LoadOptions{})
+	if err != nil {
+		t.Fatalf("Build(archive) error = %v", err)
+	}
+	if idx.DataOffset == 0 {
+		t.Error("expected messages.json to start after the first archive entry")
+	}
+
+	conv, err := idx.ReadConversation(1)
+	if err != nil {
+		t.Fatalf("ReadConversation(archive) error = %v", err)
+	}
+	if conv.Id != "c2" {
+		t.Errorf("expected c2, got %s", conv.Id)
+	}
+}
+
+func TestOpenText(t *testing.T) {
+	exportPath := writeExport(t)
+
+	idx, err := Build(exportPath, utils.LoadOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := idx.OpenText(exportPath); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound before saving, got %v", err)
+	}
+	if err := idx.Save(exportPath); err != nil {
+		t.Fatal(err)
+	}
+
+	segment, err := idx.OpenText(exportPath)
+	if err != nil {
+		t.Fatalf("OpenText error = %v", err)
+	}
+	// The ThreadActivity message is not indexed
+	if segment.DocCount() != 2 {
+		t.Errorf("expected 2 indexed messages, got %d", segment.DocCount())
+	}
+	segment.Close()
+
+	// A segment from an earlier build of the export is stale
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(exportPath, later, later); err != nil {
+		t.Fatal(err)
+	}
+	rebuilt, err := Build(exportPath, utils.LoadOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rebuilt.OpenText(exportPath); !errors.Is(err, ErrStale) {
+		t.Errorf("expected ErrStale, got %v", err)
+	}
+
+	if err := Remove(exportPath); err != nil {
+		t.Fatal(err)
+	}
+	textPath, _ := TextPath(exportPath)
+	if _, err := os.Stat(textPath); !os.IsNotExist(err) {
+		t.Errorf("expected text index to be removed, got %v", err)
+	}
+}
+
+func TestExportKey(t *testing.T) {
+	exportPath := writeExport(t)
+
+	key, err := ExportKey(exportPath)
+	if err != nil {
+		t.Fatalf("ExportKey error = %v", err)
+	}
+	if again, _ := ExportKey(exportPath); again != key {
+		t.Errorf("expected a stable key, got %q then %q", key, again)
+	}
+
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(exportPath, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if changed, _ := ExportKey(exportPath); changed == key {
+		t.Error("expected the key to change with the export")
+	}
+
+	cachePath, err := CachePath(exportPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(cachePath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(cachePath, []byte("cache"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Remove(exportPath); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(cachePath); !os.IsNotExist(err) {
+		t.Errorf("expected search cache to be removed, got %v", err)
+	}
+}
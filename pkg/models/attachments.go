@@ -0,0 +1,180 @@
+package models
+
+import (
+	"encoding/xml"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Attachment kinds
+const (
+	AttachmentFile  = "file"
+	AttachmentImage = "image"
+	AttachmentVideo = "video"
+	AttachmentAudio = "audio"
+)
+
+var uriObjectRegex = regexp.MustCompile(`(?s)<URIObject\b.*?</URIObject>`)
+
+// Attachment is a file sent with a message. Skype describes them with
+//
+//	<URIObject type="File.1" uri="https://api.asm.skype.com/v1/objects/0-weu-d1-abc"
+//	  url_thumbnail="https://api.asm.skype.com/v1/objects/0-weu-d1-abc/views/thumbnail">
+//	  <Title>Title: report.pdf</Title>
+//	  <OriginalName v="report.pdf"></OriginalName>
+//	  <FileSize v="12345"></FileSize>
+//	</URIObject>
+//
+// and list the AMS (media storage) ids of the files in amsreferences.
+type Attachment struct {
+	Kind         string // one of the Attachment* kinds
+	Name         string // original file name, empty when unknown
+	Size         int64  // in bytes, 0 when unknown
+	URI          string
+	ThumbnailURL string
+	AmsId        string
+}
+
+// uriObjectXML mirrors the XML of a URIObject
+type uriObjectXML struct {
+	Type         string `xml:"type,attr"`
+	URI          string `xml:"uri,attr"`
+	ThumbnailURL string `xml:"url_thumbnail,attr"`
+	DocId        string `xml:"doc_id,attr"`
+	Title        string `xml:"Title"`
+	OriginalName struct {
+		V string `xml:"v,attr"`
+	} `xml:"OriginalName"`
+	FileSize struct {
+		V string `xml:"v,attr"`
+	} `xml:"FileSize"`
+	Meta struct {
+		OriginalName string `xml:"originalName,attr"`
+	} `xml:"meta"`
+}
+
+// Attachments returns the files sent with the message: those described in
+// its content, followed by media references the content leaves out
+func (m *SkypeMessage) Attachments() []Attachment {
+	var attachments []Attachment
+	described := make(map[string]bool)
+	for _, object := range uriObjectRegex.FindAllString(m.Content, -1) {
+		attachment, ok := m.parseURIObject(object)
+		if !ok {
+			continue
+		}
+		if attachment.AmsId != "" {
+			described[attachment.AmsId] = true
+		}
+		attachments = append(attachments, attachment)
+	}
+
+	for _, ref := range m.AmsReferences {
+		if !described[ref] {
+			attachments = append(attachments, Attachment{Kind: m.attachmentKind(""), AmsId: ref})
+		}
+	}
+	return attachments
+}
+
+// parseURIObject reads a single <URIObject> element
+func (m *SkypeMessage) parseURIObject(object string) (Attachment, bool) {
+	decoder := xml.NewDecoder(strings.NewReader(object))
+	decoder.Strict = false
+	decoder.Entity = xml.HTMLEntity
+
+	var raw uriObjectXML
+	if err := decoder.Decode(&raw); err != nil {
+		return Attachment{}, false
+	}
+
+	attachment := Attachment{
+		Kind:         m.attachmentKind(raw.Type),
+		Name:         strings.TrimSpace(raw.OriginalName.V),
+		URI:          raw.URI,
+		ThumbnailURL: raw.ThumbnailURL,
+		AmsId:        raw.DocId,
+	}
+	if attachment.Name == "" {
+		attachment.Name = strings.TrimSpace(raw.Meta.OriginalName)
+	}
+	if attachment.Name == "" {
+		attachment.Name = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(raw.Title), "Title:"))
+	}
+	if size, err := strconv.ParseInt(strings.TrimSpace(raw.FileSize.V), 10, 64); err == nil && size > 0 {
+		attachment.Size = size
+	}
+	if attachment.AmsId == "" {
+		// The id is the last part of ".../v1/objects/<id>"
+		if _, id, ok := strings.Cut(raw.URI, "/objects/"); ok {
+			attachment.AmsId, _, _ = strings.Cut(id, "/")
+		}
+	}
+	return attachment, true
+}
+
+// attachmentKind tells what an attachment is from the type of its
+// URIObject, such as "Picture.1", or else from the message type
+func (m *SkypeMessage) attachmentKind(objectType string) string {
+	objectType, _, _ = strings.Cut(objectType, ".")
+	switch strings.ToLower(objectType) {
+	case "picture":
+		return AttachmentImage
+	case "video":
+		return AttachmentVideo
+	case "audio":
+		return AttachmentAudio
+	case "file":
+		return AttachmentFile
+	}
+
+	switch m.MessageType {
+	case "RichText/UriObject":
+		return AttachmentImage
+	case "RichText/Media_Video":
+		return AttachmentVideo
+	case "RichText/Media_AudioMsg":
+		return AttachmentAudio
+	}
+	return AttachmentFile
+}
+
+// attachmentText replaces each <URIObject> of content with the name of the
+// file it describes
+func (m *SkypeMessage) attachmentText(content string) string {
+	return uriObjectRegex.ReplaceAllStringFunc(content, func(object string) string {
+		if attachment, ok := m.parseURIObject(object); ok {
+			return " " + html.EscapeString(attachment.Name) + " "
+		}
+		return ""
+	})
+}
+
+// Describe names the attachment along with its kind and size, such as
+// "report.pdf (file, 12.1 KB)"
+func (a *Attachment) Describe() string {
+	name := a.Name
+	if name == "" {
+		name = "Unnamed " + a.Kind
+	}
+	if a.Size <= 0 {
+		return name + " (" + a.Kind + ")"
+	}
+	return name + " (" + a.Kind + ", " + formatSize(a.Size) + ")"
+}
+
+// formatSize formats a size in bytes with a binary unit
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return strconv.FormatInt(size, 10) + " B"
+	}
+	value, exponent := float64(size)/unit, 0
+	for value >= unit && exponent < 3 {
+		value /= unit
+		exponent++
+	}
+	return strconv.FormatFloat(value, 'f', 1, 64) + " " + []string{"KB", "MB", "GB", "TB"}[exponent]
+}
@@ -0,0 +1,94 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+const fileObject = `<URIObject type="File.1" uri="https://api.asm.skype.com/v1/objects/0-weu-d1-abc" ` +
+	`url_thumbnail="https://api.asm.skype.com/v1/objects/0-weu-d1-abc/views/thumbnail">` +
+	`To view this file, go to: <a href="https://login.skype.com/login/sso?go=webclient.xmm&amp;docid=0-weu-d1-abc">https://login.skype.com/login/sso?go=webclient.xmm&amp;docid=0-weu-d1-abc</a>` +
+	`<Title>Title: Q1 report.pdf</Title><Description> Description: Q1 report.pdf</Description>` +
+	`<OriginalName v="Q1 report.pdf"></OriginalName><FileSize v="12645"></FileSize></URIObject>`
+
+func TestSkypeMessage_AttachmentsFromContent(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  SkypeMessage
+		want []Attachment
+	}{
+		{
+			name: "file",
+			msg:  SkypeMessage{MessageType: "RichText/Media_GenericFile", Content: fileObject, AmsReferences: []string{"0-weu-d1-abc"}},
+			want: []Attachment{{
+				Kind:         AttachmentFile,
+				Name:         "Q1 report.pdf",
+				Size:         12645,
+				URI:          "https://api.asm.skype.com/v1/objects/0-weu-d1-abc",
+				ThumbnailURL: "https://api.asm.skype.com/v1/objects/0-weu-d1-abc/views/thumbnail",
+				AmsId:        "0-weu-d1-abc",
+			}},
+		},
+		{
+			name: "picture named by its meta",
+			msg: SkypeMessage{MessageType: "RichText/UriObject",
+				Content: `<URIObject type="Picture.1" uri="https://api.asm.skype.com/v1/objects/0-pic" doc_id="0-pic"><meta type="photo" originalName="IMG_01.jpg"/></URIObject>`},
+			want: []Attachment{{Kind: AttachmentImage, Name: "IMG_01.jpg", URI: "https://api.asm.skype.com/v1/objects/0-pic", AmsId: "0-pic"}},
+		},
+		{
+			name: "titled video",
+			msg: SkypeMessage{MessageType: "RichText/Media_Video",
+				Content: `<URIObject type="Video.1/Flik.1" uri="https://api.asm.skype.com/v1/objects/0-vid/views/video"><Title>Title: clip.mp4</Title></URIObject>`},
+			want: []Attachment{{Kind: AttachmentVideo, Name: "clip.mp4", URI: "https://api.asm.skype.com/v1/objects/0-vid/views/video", AmsId: "0-vid"}},
+		},
+		{
+			name: "references only",
+			msg:  SkypeMessage{MessageType: "RichText/Media_AudioMsg", Content: "Voice message", AmsReferences: []string{"ref1"}},
+			want: []Attachment{{Kind: AttachmentAudio, AmsId: "ref1"}},
+		},
+		{
+			name: "plain message",
+			msg:  SkypeMessage{Content: "hello"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.msg.Attachments(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Attachments() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSkypeMessage_AttachmentText(t *testing.T) {
+	msg := SkypeMessage{MessageType: "RichText/Media_GenericFile", Content: "Here it is " + fileObject}
+
+	if got := msg.GetDisplayText(); got != "Here it is  Q1 report.pdf" {
+		t.Errorf("GetDisplayText() = %q", got)
+	}
+	if got := msg.BodyText(); got != "Here it is" {
+		t.Errorf("BodyText() = %q", got)
+	}
+	if !msg.HasAttachments() {
+		t.Error("expected a described file to count as an attachment")
+	}
+}
+
+func TestAttachment_Describe(t *testing.T) {
+	tests := []struct {
+		attachment Attachment
+		want       string
+	}{
+		{Attachment{Kind: AttachmentFile, Name: "report.pdf", Size: 12645}, "report.pdf (file, 12.3 KB)"},
+		{Attachment{Kind: AttachmentVideo, Name: "clip.mp4", Size: 5 << 20}, "clip.mp4 (video, 5.0 MB)"},
+		{Attachment{Kind: AttachmentImage, Name: "a.png", Size: 512}, "a.png (image, 512 B)"},
+		{Attachment{Kind: AttachmentAudio}, "Unnamed audio (audio)"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.attachment.Describe(); got != tt.want {
+			t.Errorf("Describe() = %q, want %q", got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package models
+
+import (
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CallMessageType is the message type of call events
+const CallMessageType = "Event/Call"
+
+// Call event types, from the type attribute of <partlist>
+const (
+	CallStarted = "started"
+	CallEnded   = "ended"
+	CallMissed  = "missed"
+)
+
+// CallEvent is the payload of an Event/Call message, such as
+//
+//	<partlist type="ended" alt="" callId="...">
+//	  <part identity="8:live:alice"><name>Alice</name><duration>125</duration></part>
+//	</partlist>
+type CallEvent struct {
+	Type         string // CallStarted, CallEnded, CallMissed or another type as written
+	CallId       string
+	Participants []CallParticipant
+}
+
+// CallParticipant is one <part> of a call event
+type CallParticipant struct {
+	Id       string
+	Name     string
+	Duration time.Duration // time spent in the call, zero when not reported
+}
+
+// callPartList mirrors the XML of a call event
+type callPartList struct {
+	Type   string `xml:"type,attr"`
+	CallId string `xml:"callId,attr"`
+	Parts  []struct {
+		Identity string `xml:"identity,attr"`
+		Name     string `xml:"name"`
+		Duration string `xml:"duration"`
+	} `xml:"part"`
+}
+
+// IsCallEvent reports whether the message records a call
+func (m *SkypeMessage) IsCallEvent() bool {
+	return m.MessageType == CallMessageType
+}
+
+// ParseCallEvent parses the <partlist> payload of a call event
+func (m *SkypeMessage) ParseCallEvent() (*CallEvent, error) {
+	if !m.IsCallEvent() {
+		return nil, fmt.Errorf("not a call event: %s", m.MessageType)
+	}
+	if !strings.Contains(m.Content, "<partlist") {
+		return nil, fmt.Errorf("call event without a participant list")
+	}
+
+	// Names may carry HTML entities that strict XML rejects
+	decoder := xml.NewDecoder(strings.NewReader(m.Content))
+	decoder.Strict = false
+	decoder.Entity = xml.HTMLEntity
+
+	var partList callPartList
+	if err := decoder.Decode(&partList); err != nil {
+		return nil, fmt.Errorf("failed to parse call event: %w", err)
+	}
+
+	event := &CallEvent{Type: partList.Type, CallId: partList.CallId}
+	for _, part := range partList.Parts {
+		participant := CallParticipant{Id: part.Identity, Name: strings.TrimSpace(part.Name)}
+		if seconds, err := strconv.ParseFloat(strings.TrimSpace(part.Duration), 64); err == nil && seconds > 0 {
+			participant.Duration = time.Duration(seconds * float64(time.Second))
+		}
+		event.Participants = append(event.Participants, participant)
+	}
+	return event, nil
+}
+
+// Duration returns how long the call lasted, the longest time any
+// participant spent in it
+func (e *CallEvent) Duration() time.Duration {
+	var longest time.Duration
+	for _, participant := range e.Participants {
+		longest = max(longest, participant.Duration)
+	}
+	return longest
+}
+
+// ParticipantNames returns the names of the participants, or their ids
+// when unnamed
+func (e *CallEvent) ParticipantNames() []string {
+	return participantNames(e.Participants)
+}
+
+// Describe summarizes the event in a sentence
+func (e *CallEvent) Describe() string {
+	participants := strings.Join(e.ParticipantNames(), ", ")
+	switch e.Type {
+	case CallStarted:
+		return "Call started by " + participants
+	case CallMissed:
+		return "Missed call from " + participants
+	case CallEnded:
+		if duration := e.Duration(); duration > 0 {
+			return fmt.Sprintf("Call ended after %s with %s", duration.Round(time.Second), participants)
+		}
+		return "Call ended without being answered"
+	default:
+		return fmt.Sprintf("Call %s: %s", e.Type, participants)
+	}
+}
+
+// Call is a call pieced together from the events it produced
+type Call struct {
+	CallId       string
+	Start        time.Time // zero when unknown
+	End          time.Time // zero while no end event was seen
+	Duration     time.Duration
+	Participants []CallParticipant
+	Missed       bool // missed, or ended without anyone answering
+}
+
+// ParticipantNames returns the names of the participants, or their ids
+// when unnamed
+func (c *Call) ParticipantNames() []string {
+	return participantNames(c.Participants)
+}
+
+func participantNames(participants []CallParticipant) []string {
+	names := make([]string, 0, len(participants))
+	for _, participant := range participants {
+		if participant.Name != "" {
+			names = append(names, participant.Name)
+		} else {
+			names = append(names, participant.Id)
+		}
+	}
+	return names
+}
+
+// Calls returns the calls of the conversation in chronological order.
+// Start and end events are matched by call id, or with the latest call
+// still going on when the export leaves the id out. Events that can't be
+// parsed are skipped.
+func (c *SkypeConversation) Calls() []Call {
+	type callEvent struct {
+		at    time.Time
+		event *CallEvent
+	}
+
+	var events []callEvent
+	for i := range c.MessageList {
+		msg := &c.MessageList[i]
+		if !msg.IsCallEvent() {
+			continue
+		}
+		event, err := msg.ParseCallEvent()
+		if err != nil {
+			continue
+		}
+		at, _ := msg.GetTimestamp()
+		events = append(events, callEvent{at: at, event: event})
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].at.Before(events[j].at)
+	})
+
+	var calls []Call
+	open := make(map[int]bool) // indexes into calls still going on
+	findOpen := func(callId string) int {
+		found := -1
+		for i := range calls {
+			if open[i] && (callId == "" || calls[i].CallId == callId) {
+				found = i
+			}
+		}
+		return found
+	}
+
+	for _, e := range events {
+		switch e.event.Type {
+		case CallStarted:
+			calls = append(calls, Call{CallId: e.event.CallId, Start: e.at, Participants: e.event.Participants})
+			open[len(calls)-1] = true
+
+		case CallEnded, CallMissed:
+			duration := e.event.Duration()
+			i := findOpen(e.event.CallId)
+			if i == -1 {
+				// Without a start event, the start is known from the duration
+				calls = append(calls, Call{CallId: e.event.CallId, Start: e.at.Add(-duration)})
+				i = len(calls) - 1
+			}
+			delete(open, i)
+
+			call := &calls[i]
+			call.End = e.at
+			call.Duration = duration
+			if len(e.event.Participants) > 0 {
+				call.Participants = e.event.Participants
+			}
+			call.Missed = e.event.Type == CallMissed || duration == 0
+		}
+	}
+	return calls
+}
@@ -0,0 +1,104 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSkypeMessage_ParseCallEvent(t *testing.T) {
+	tests := []struct {
+		name    string
+		msg     SkypeMessage
+		want    *CallEvent
+		wantErr bool
+	}{
+		{
+			name: "ended call",
+			msg: SkypeMessage{MessageType: "Event/Call", Content: `<partlist type="ended" alt="" callId="c1">` +
+				`<part identity="8:live:alice"><name>Alice &amp; Co</name><duration>125</duration></part>` +
+				`<part identity="8:live:bob"><name>Bob</name><duration>90.5</duration></part></partlist>`},
+			want: &CallEvent{Type: CallEnded, CallId: "c1", Participants: []CallParticipant{
+				{Id: "8:live:alice", Name: "Alice & Co", Duration: 125 * time.Second},
+				{Id: "8:live:bob", Name: "Bob", Duration: 90500 * time.Millisecond},
+			}},
+		},
+		{
+			name: "started call with html entity",
+			msg: SkypeMessage{MessageType: "Event/Call", Content: `<partlist type="started" alt="">` +
+				`<part identity="8:live:carol"><name>Carol&nbsp;D</name></part></partlist>`},
+			want: &CallEvent{Type: CallStarted, Participants: []CallParticipant{
+				{Id: "8:live:carol", Name: "Carol D"},
+			}},
+		},
+		{name: "plain text", msg: SkypeMessage{MessageType: "Event/Call", Content: "Call ended."}, wantErr: true},
+		{name: "not a call", msg: SkypeMessage{MessageType: "RichText", Content: `<partlist type="ended"></partlist>`}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.msg.ParseCallEvent()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseCallEvent() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseCallEvent() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCallEvent_Describe(t *testing.T) {
+	alice := CallParticipant{Id: "8:live:alice", Name: "Alice", Duration: 65 * time.Second}
+	tests := []struct {
+		event CallEvent
+		want  string
+	}{
+		{CallEvent{Type: CallStarted, Participants: []CallParticipant{{Id: "8:live:alice"}}}, "Call started by 8:live:alice"},
+		{CallEvent{Type: CallEnded, Participants: []CallParticipant{alice}}, "Call ended after 1m5s with Alice"},
+		{CallEvent{Type: CallEnded, Participants: []CallParticipant{{Name: "Alice"}}}, "Call ended without being answered"},
+		{CallEvent{Type: CallMissed, Participants: []CallParticipant{{Name: "Bob"}}}, "Missed call from Bob"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.event.Describe(); got != tt.want {
+			t.Errorf("Describe() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestSkypeConversation_Calls(t *testing.T) {
+	call := func(id, timestamp, content string) SkypeMessage {
+		return SkypeMessage{OriginalId: id, MessageType: "Event/Call", Timestamp: timestamp, Content: content}
+	}
+	conv := SkypeConversation{MessageList: []SkypeMessage{
+		call("m1", "2024-01-01T10:00:00Z", `<partlist type="started" callId="a"><part identity="8:alice"><name>Alice</name></part></partlist>`),
+		{OriginalId: "m2", MessageType: "RichText", Timestamp: "2024-01-01T10:01:00Z", Content: "hi"},
+		call("m3", "2024-01-01T10:05:00Z", `<partlist type="ended" callId="a"><part identity="8:alice"><name>Alice</name><duration>300</duration></part>`+
+			`<part identity="8:bob"><name>Bob</name><duration>240</duration></part></partlist>`),
+		call("m4", "2024-01-02T09:00:00Z", `<partlist type="missed"><part identity="8:bob"><name>Bob</name></part></partlist>`),
+		call("m5", "2024-01-03T09:00:00Z", "Call ended."),
+		call("m6", "2024-01-04T09:10:00Z", `<partlist type="ended"><part identity="8:bob"><name>Bob</name><duration>600</duration></part></partlist>`),
+	}}
+
+	calls := conv.Calls()
+	if len(calls) != 3 {
+		t.Fatalf("expected 3 calls, got %+v", calls)
+	}
+
+	first := calls[0]
+	if first.CallId != "a" || first.Start.Format(time.RFC3339) != "2024-01-01T10:00:00Z" ||
+		first.End.Format(time.RFC3339) != "2024-01-01T10:05:00Z" || first.Duration != 5*time.Minute ||
+		len(first.Participants) != 2 || first.Missed {
+		t.Errorf("unexpected first call: %+v", first)
+	}
+
+	if missed := calls[1]; !missed.Missed || missed.Duration != 0 || missed.Participants[0].Name != "Bob" {
+		t.Errorf("unexpected missed call: %+v", missed)
+	}
+
+	// Without a start event, the start is worked out from the duration
+	if last := calls[2]; last.Start.Format(time.RFC3339) != "2024-01-04T09:00:00Z" || last.Missed {
+		t.Errorf("unexpected last call: %+v", last)
+	}
+}
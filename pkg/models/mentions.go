@@ -0,0 +1,72 @@
+package models
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+// MentionEveryone is the id of @all mentions, which mention every member
+const MentionEveryone = "*"
+
+var (
+	mentionRegex   = regexp.MustCompile(`(?s)<at\b([^>]*)>(.*?)</at>`)
+	mentionIdRegex = regexp.MustCompile(`\bid="([^"]*)"`)
+)
+
+// Mention is a member mentioned in a message with <at id="8:live:alice">Alice</at>
+type Mention struct {
+	Id   string
+	Name string // as written in the message, without the @
+}
+
+// Mentions returns the members the message mentions, in order, each once.
+// Mentions within the messages it quotes belong to those and don't count.
+func (m *SkypeMessage) Mentions() []Mention {
+	if !strings.Contains(m.Content, "<at") {
+		return nil
+	}
+	content := m.Content
+	if m.HasQuotes() {
+		content = removeQuotes(content)
+	}
+
+	var mentions []Mention
+	seen := make(map[string]bool)
+	for _, match := range mentionRegex.FindAllStringSubmatch(content, -1) {
+		mention := Mention{Name: cleanText(match[2])}
+		if id := mentionIdRegex.FindStringSubmatch(match[1]); id != nil {
+			mention.Id = html.UnescapeString(id[1])
+		}
+		if mention.Id == "" || seen[mention.Id] {
+			continue
+		}
+		seen[mention.Id] = true
+		mentions = append(mentions, mention)
+	}
+	return mentions
+}
+
+// MentionedIds returns the ids of the members the message mentions, nil
+// when there are none
+func (m *SkypeMessage) MentionedIds() []string {
+	var ids []string
+	for _, mention := range m.Mentions() {
+		ids = append(ids, mention.Id)
+	}
+	return ids
+}
+
+// MentionsUser reports whether the message mentions userId, directly or with
+// @all. As with IsSentBy, userId may lack the network prefix of mentions.
+func (m *SkypeMessage) MentionsUser(userId string) bool {
+	if userId == "" {
+		return false
+	}
+	for _, mention := range m.Mentions() {
+		if mention.Id == MentionEveryone || sameUser(mention.Id, userId) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSkypeMessage_Mentions(t *testing.T) {
+	msg := SkypeMessage{Content: `Hi <at id="8:live:alice">Alice</at>, <at id="8:live:bob">Bob &amp; co</at> and <at id="8:live:alice">Alice</at>`}
+
+	want := []Mention{{Id: "8:live:alice", Name: "Alice"}, {Id: "8:live:bob", Name: "Bob & co"}}
+	if got := msg.Mentions(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Mentions() = %+v, want %+v", got, want)
+	}
+	if got := msg.MentionedIds(); !reflect.DeepEqual(got, []string{"8:live:alice", "8:live:bob"}) {
+		t.Errorf("MentionedIds() = %q", got)
+	}
+	if got := msg.GetDisplayText(); got != "Hi @Alice, @Bob & co and @Alice" {
+		t.Errorf("GetDisplayText() = %q", got)
+	}
+
+	quoted := SkypeMessage{Content: `<quote author="8:live:bob" authorname="Bob" timestamp="1704103200">` +
+		`<legacyquote>[1704103200] Bob: </legacyquote><at id="8:live:carol">Carol</at> are you in?<legacyquote>&lt;&lt;&lt; </legacyquote></quote>` +
+		`Yes, <at id="8:live:alice">Alice</at> too`}
+	if got := quoted.MentionedIds(); !reflect.DeepEqual(got, []string{"8:live:alice"}) {
+		t.Errorf("expected quoted mentions to be left out, got %q", got)
+	}
+	if quoted.MentionsUser("live:carol") {
+		t.Error("expected a mention within a quote not to count")
+	}
+
+	plain := SkypeMessage{Content: "no mentions"}
+	if plain.Mentions() != nil || plain.MentionedIds() != nil {
+		t.Error("expected no mentions")
+	}
+}
+
+func TestSkypeMessage_MentionsUser(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		userId  string
+		want    bool
+	}{
+		{"exact id", `<at id="8:live:alice">Alice</at>`, "8:live:alice", true},
+		{"id without prefix", `<at id="8:live:alice">Alice</at>`, "live:alice", true},
+		{"someone else", `<at id="8:live:bob">Bob</at>`, "live:alice", false},
+		{"everyone", `<at id="*">all</at>`, "live:alice", true},
+		{"no user", `<at id="*">all</at>`, "", false},
+		{"plain text", "@alice", "live:alice", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := SkypeMessage{Content: tt.content}
+			if got := msg.MentionsUser(tt.userId); got != tt.want {
+				t.Errorf("MentionsUser(%q) = %v, want %v", tt.userId, got, tt.want)
+			}
+		})
+	}
+}
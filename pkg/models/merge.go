@@ -0,0 +1,111 @@
+package models
+
+import (
+	"sort"
+	"strings"
+)
+
+// MergeHistories unions several exports into one history. Conversations are
+// matched by Id and keep the metadata (display name, properties and
+// threadProperties) of the copy with the highest version. Messages are
+// deduplicated by OriginalId, keeping the highest Version, and sorted by
+// arrival time. The first non-empty userId and the latest export date win.
+func MergeHistories(histories ...*SkypeHistoryRoot) *SkypeHistoryRoot {
+	merged := &SkypeHistoryRoot{}
+
+	var order []string
+	conversations := make(map[string]*conversationMerge)
+
+	for _, history := range histories {
+		if history == nil {
+			continue
+		}
+		if merged.UserId == "" {
+			merged.UserId = history.UserId
+		}
+		if history.ExportDate > merged.ExportDate {
+			merged.ExportDate = history.ExportDate
+		}
+
+		for i := range history.Conversations {
+			conv := &history.Conversations[i]
+			cm, ok := conversations[conv.Id]
+			if !ok {
+				cm = &conversationMerge{messages: make(map[string]int)}
+				conversations[conv.Id] = cm
+				order = append(order, conv.Id)
+			}
+			cm.add(conv)
+		}
+	}
+
+	merged.Conversations = make([]SkypeConversation, 0, len(order))
+	for _, id := range order {
+		merged.Conversations = append(merged.Conversations, conversations[id].result())
+	}
+
+	return merged
+}
+
+// conversationMerge accumulates every copy of one conversation
+type conversationMerge struct {
+	conv     SkypeConversation
+	seen     bool
+	messages map[string]int // dedup key -> index in conv.MessageList
+}
+
+func (cm *conversationMerge) add(conv *SkypeConversation) {
+	// Later copies win ties so that the most recent export supplies metadata
+	if !cm.seen || conv.Version >= cm.conv.Version {
+		previous := cm.conv
+		cm.conv = *conv
+		cm.conv.MessageList = previous.MessageList
+
+		// Don't lose metadata the newer copy happens to omit
+		if cm.conv.DisplayName == nil {
+			cm.conv.DisplayName = previous.DisplayName
+		}
+		if cm.conv.Properties == nil {
+			cm.conv.Properties = previous.Properties
+		}
+		if cm.conv.ThreadProperties == nil {
+			cm.conv.ThreadProperties = previous.ThreadProperties
+		}
+		cm.seen = true
+	}
+
+	for _, msg := range conv.MessageList {
+		key := messageKey(&msg)
+		if i, ok := cm.messages[key]; ok {
+			if msg.Version > cm.conv.MessageList[i].Version {
+				cm.conv.MessageList[i] = msg
+			}
+			continue
+		}
+		cm.messages[key] = len(cm.conv.MessageList)
+		cm.conv.MessageList = append(cm.conv.MessageList, msg)
+	}
+}
+
+func (cm *conversationMerge) result() SkypeConversation {
+	messages := cm.conv.MessageList
+	sort.SliceStable(messages, func(i, j int) bool {
+		ti, errI := messages[i].GetTimestamp()
+		tj, errJ := messages[j].GetTimestamp()
+		if errI != nil {
+			// Messages with unreadable timestamps go last
+			return false
+		}
+		return errJ != nil || ti.Before(tj)
+	})
+	return cm.conv
+}
+
+// messageKey identifies a message across exports, falling back to its
+// sender, time and content when it has no id
+func messageKey(msg *SkypeMessage) string {
+	if msg.OriginalId != "" {
+		return "id:" + msg.OriginalId
+	}
+	return strings.Join([]string{"msg", msg.From, msg.Timestamp, msg.Content}, "\x00")
+}
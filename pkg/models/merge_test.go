@@ -0,0 +1,93 @@
+package models
+
+import "testing"
+
+func TestMergeHistories(t *testing.T) {
+	oldTopic := "Old topic"
+	newTopic := "New topic"
+
+	older := &SkypeHistoryRoot{
+		UserId:     "8:live:me",
+		ExportDate: "2022-01-01T00:00:00Z",
+		Conversations: []SkypeConversation{
+			{
+				Id:               "19:group@thread.skype",
+				Version:          1,
+				ThreadProperties: &ThreadProperties{Topic: &oldTopic},
+				MessageList: []SkypeMessage{
+					{OriginalId: "m1", Content: "first", Version: 1, Timestamp: "2021-01-01T10:00:00Z"},
+					{OriginalId: "m2", Content: "draft", Version: 1, Timestamp: "2021-01-01T11:00:00Z"},
+				},
+			},
+			{
+				Id:          "8:live:friend",
+				MessageList: []SkypeMessage{{OriginalId: "f1", Timestamp: "2021-06-01T10:00:00Z"}},
+			},
+		},
+	}
+
+	newer := &SkypeHistoryRoot{
+		UserId:     "8:live:colleague",
+		ExportDate: "2024-01-01T00:00:00Z",
+		Conversations: []SkypeConversation{
+			{
+				Id:               "19:group@thread.skype",
+				Version:          2,
+				ThreadProperties: &ThreadProperties{Topic: &newTopic},
+				MessageList: []SkypeMessage{
+					{OriginalId: "m3", Content: "latest", Version: 1, Timestamp: "2023-01-01T10:00:00Z"},
+					{OriginalId: "m2", Content: "edited", Version: 2, Timestamp: "2021-01-01T11:00:00Z"},
+					{OriginalId: "m1", Content: "first", Version: 1, Timestamp: "2021-01-01T10:00:00Z"},
+				},
+			},
+		},
+	}
+
+	merged := MergeHistories(older, newer)
+
+	if merged.UserId != "8:live:me" {
+		t.Errorf("expected first userId to win, got %s", merged.UserId)
+	}
+	if merged.ExportDate != "2024-01-01T00:00:00Z" {
+		t.Errorf("expected latest export date, got %s", merged.ExportDate)
+	}
+	if len(merged.Conversations) != 2 {
+		t.Fatalf("expected 2 conversations, got %d", len(merged.Conversations))
+	}
+
+	group := merged.Conversations[0]
+	if group.ThreadProperties == nil || *group.ThreadProperties.Topic != newTopic {
+		t.Errorf("expected newest threadProperties to be kept")
+	}
+	if len(group.MessageList) != 3 {
+		t.Fatalf("expected 3 deduplicated messages, got %d", len(group.MessageList))
+	}
+
+	wantOrder := []string{"m1", "m2", "m3"}
+	for i, want := range wantOrder {
+		if group.MessageList[i].OriginalId != want {
+			t.Errorf("message %d = %s, want %s", i, group.MessageList[i].OriginalId, want)
+		}
+	}
+	if group.MessageList[1].Content != "edited" {
+		t.Errorf("expected highest version of m2 to be kept, got %q", group.MessageList[1].Content)
+	}
+}
+
+func TestMergeHistoriesKeepsMetadataMissingFromNewerCopy(t *testing.T) {
+	topic := "Kept"
+	older := &SkypeHistoryRoot{Conversations: []SkypeConversation{
+		{Id: "c", Version: 1, ThreadProperties: &ThreadProperties{Topic: &topic}},
+	}}
+	newer := &SkypeHistoryRoot{Conversations: []SkypeConversation{
+		{Id: "c", Version: 2},
+	}}
+
+	merged := MergeHistories(older, nil, newer)
+	if merged.Conversations[0].ThreadProperties == nil || *merged.Conversations[0].ThreadProperties.Topic != topic {
+		t.Error("expected threadProperties from the older copy to be kept")
+	}
+	if merged.Conversations[0].Version != 2 {
+		t.Errorf("expected newest version, got %d", merged.Conversations[0].Version)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"html"
 	"regexp"
+	"slices"
 	"strings"
 	"time"
 )
@@ -60,6 +61,16 @@ type ThreadProperties struct {
 	Description *string `json:"description"`
 }
 
+// ConversationSummary holds the per-conversation figures shown in listings
+type ConversationSummary struct {
+	Id                 string
+	DisplayName        string
+	ParticipantCount   int
+	MessageCount       int
+	SystemMessageCount int
+	LastMessage        time.Time // zero when no message has a valid timestamp
+}
+
 // SkypeHistoryRoot represents the root structure of Skype export
 type SkypeHistoryRoot struct {
 	UserId        string              `json:"userId"`
@@ -67,10 +78,44 @@ type SkypeHistoryRoot struct {
 	Conversations []SkypeConversation `json:"conversations"`
 }
 
-// GetDisplayText returns clean text without HTML/XML tags
+// GetDisplayText returns clean text without HTML/XML tags. Quoted messages
+// come first on their own lines, prefixed with "> ", mentions read "@Name"
+// and attached files are named.
 func (m *SkypeMessage) GetDisplayText() string {
+	content := m.Content
+	if m.HasQuotes() {
+		content = replaceQuotes(content, func(quote quoteElement) string {
+			return quotedLines(quote.body)
+		})
+	}
+	if strings.Contains(content, "<URIObject") {
+		content = m.attachmentText(content)
+	}
+	return cleanText(content)
+}
+
+// BodyText returns the clean text the sender wrote, without the messages it
+// quotes or the files it attaches
+func (m *SkypeMessage) BodyText() string {
+	content := m.Content
+	if m.HasQuotes() {
+		content = removeQuotes(content)
+	}
+	if strings.Contains(content, "<URIObject") {
+		content = uriObjectRegex.ReplaceAllString(content, "")
+	}
+	return cleanText(content)
+}
+
+// cleanText strips the tags of HTML/XML content and unescapes its
+// entities, writing mentions as "@Name"
+func cleanText(content string) string {
+	if strings.Contains(content, "<at") {
+		content = mentionRegex.ReplaceAllString(content, "@$2")
+	}
+
 	// Remove HTML tags
-	cleanContent := htmlTagRegex.ReplaceAllString(m.Content, "")
+	cleanContent := htmlTagRegex.ReplaceAllString(content, "")
 
 	// Unescape HTML entities
 	cleanContent = html.UnescapeString(cleanContent)
@@ -92,6 +137,32 @@ func (m *SkypeMessage) IsSystemMessage() bool {
 		strings.Contains(m.MessageType, "Control")
 }
 
+// HasAttachments reports whether the message references uploaded media
+func (m *SkypeMessage) HasAttachments() bool {
+	return len(m.AmsReferences) > 0 || strings.Contains(m.Content, "<URIObject")
+}
+
+// HasUrlPreviews reports whether the message carries link previews
+func (m *SkypeMessage) HasUrlPreviews() bool {
+	return m.Properties != nil && m.Properties.UrlPreviews != nil && *m.Properties.UrlPreviews != ""
+}
+
+// IsSentBy reports whether the message was sent by userId. Senders carry
+// a network prefix ("8:live:alice") that the export's userId may lack.
+func (m *SkypeMessage) IsSentBy(userId string) bool {
+	return userId != "" && sameUser(m.From, userId)
+}
+
+// sameUser reports whether id, which carries a network prefix, names
+// userId, which may lack it
+func sameUser(id, userId string) bool {
+	if id == userId {
+		return true
+	}
+	prefix, rest, ok := strings.Cut(id, ":")
+	return ok && rest == userId && prefix != "" && strings.Trim(prefix, "0123456789") == ""
+}
+
 // GetTimestamp parses and returns the message timestamp
 func (m *SkypeMessage) GetTimestamp() (time.Time, error) {
 	if m.timestampParsed {
@@ -162,3 +233,43 @@ func (c *SkypeConversation) FilterSystemMessages() []SkypeMessage {
 	}
 	return filtered
 }
+
+// SurroundingMessages returns up to before non-system messages preceding
+// the message at index i and up to after following it, in order
+func (c *SkypeConversation) SurroundingMessages(i, before, after int) (preceding, following []SkypeMessage) {
+	for j := i - 1; j >= 0 && len(preceding) < before; j-- {
+		if !c.MessageList[j].IsSystemMessage() {
+			preceding = append(preceding, c.MessageList[j])
+		}
+	}
+	slices.Reverse(preceding)
+
+	for j := i + 1; j < len(c.MessageList) && len(following) < after; j++ {
+		if !c.MessageList[j].IsSystemMessage() {
+			following = append(following, c.MessageList[j])
+		}
+	}
+	return preceding, following
+}
+
+// Summary computes the listing figures for the conversation
+func (c *SkypeConversation) Summary() ConversationSummary {
+	summary := ConversationSummary{
+		Id:               c.Id,
+		DisplayName:      c.GetConversationDisplayName(),
+		ParticipantCount: c.GetParticipantCount(),
+		MessageCount:     len(c.MessageList),
+	}
+
+	for i := range c.MessageList {
+		msg := &c.MessageList[i]
+		if msg.IsSystemMessage() {
+			summary.SystemMessageCount++
+		}
+		if t, err := msg.GetTimestamp(); err == nil && t.After(summary.LastMessage) {
+			summary.LastMessage = t
+		}
+	}
+
+	return summary
+}
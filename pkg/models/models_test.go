@@ -1,6 +1,7 @@
 package models
 
 import (
+	"strings"
 	"testing"
 	"time"
 )
@@ -269,6 +270,123 @@ func TestSkypeMessage_GetSenderDisplayName(t *testing.T) {
 	}
 }
 
+func TestSkypeConversation_Summary(t *testing.T) {
+	c := &SkypeConversation{
+		Id:          "conv1",
+		DisplayName: stringPtr("Friends"),
+		MessageList: []SkypeMessage{
+			{From: "Alice", MessageType: "Text", Timestamp: "2024-01-01T12:00:00Z"},
+			{From: "Bob", MessageType: "Control/ThreadActivity", Timestamp: "2024-01-01T13:00:00Z"},
+			{From: "Alice", MessageType: "Text", Timestamp: "invalid"},
+		},
+	}
+
+	summary := c.Summary()
+	if summary.DisplayName != "Friends" || summary.ParticipantCount != 2 {
+		t.Errorf("unexpected summary: %+v", summary)
+	}
+	if summary.MessageCount != 3 || summary.SystemMessageCount != 1 {
+		t.Errorf("expected 3 messages with 1 system message, got %d/%d", summary.MessageCount, summary.SystemMessageCount)
+	}
+	if !summary.LastMessage.Equal(time.Date(2024, 1, 1, 13, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected last message time: %v", summary.LastMessage)
+	}
+
+	if empty := (&SkypeConversation{}).Summary(); !empty.LastMessage.IsZero() {
+		t.Errorf("expected zero last message time for empty conversation, got %v", empty.LastMessage)
+	}
+}
+
+func TestSkypeMessage_IsSentBy(t *testing.T) {
+	tests := []struct {
+		from   string
+		userId string
+		want   bool
+	}{
+		{"8:live:alice", "live:alice", true},
+		{"8:live:alice", "8:live:alice", true},
+		{"live:alice", "live:alice", true},
+		{"8:live:bob", "live:alice", false},
+		{"x:live:alice", "live:alice", false},
+		{"8:live:alice", "", false},
+	}
+
+	for _, tt := range tests {
+		m := &SkypeMessage{From: tt.from}
+		if got := m.IsSentBy(tt.userId); got != tt.want {
+			t.Errorf("IsSentBy(%q) with From %q = %v, want %v", tt.userId, tt.from, got, tt.want)
+		}
+	}
+}
+
+func TestSkypeMessage_Attachments(t *testing.T) {
+	empty := ""
+	previews := `[{"url":"https://example.com"}]`
+
+	m := &SkypeMessage{}
+	if m.HasAttachments() || m.HasUrlPreviews() {
+		t.Error("expected a plain message to have no attachments or previews")
+	}
+
+	m = &SkypeMessage{
+		AmsReferences: []string{"0-weu-d1-abc"},
+		Properties:    &MessageProperties{UrlPreviews: &previews},
+	}
+	if !m.HasAttachments() || !m.HasUrlPreviews() {
+		t.Error("expected attachments and previews")
+	}
+
+	m = &SkypeMessage{Properties: &MessageProperties{UrlPreviews: &empty}}
+	if m.HasUrlPreviews() {
+		t.Error("expected an empty preview list to count as none")
+	}
+}
+
+func TestSkypeConversation_SurroundingMessages(t *testing.T) {
+	c := &SkypeConversation{
+		MessageList: []SkypeMessage{
+			{OriginalId: "m1", MessageType: "Text"},
+			{OriginalId: "m2", MessageType: "Text"},
+			{OriginalId: "s1", MessageType: "Control/ThreadActivity"},
+			{OriginalId: "m3", MessageType: "Text"},
+			{OriginalId: "m4", MessageType: "Text"},
+		},
+	}
+
+	ids := func(messages []SkypeMessage) string {
+		var parts []string
+		for _, m := range messages {
+			parts = append(parts, m.OriginalId)
+		}
+		return strings.Join(parts, ",")
+	}
+
+	tests := []struct {
+		name          string
+		i             int
+		before, after int
+		wantBefore    string
+		wantAfter     string
+	}{
+		{"skips system messages", 3, 2, 1, "m1,m2", "m4"},
+		{"clamped at the start", 0, 3, 1, "", "m2"},
+		{"clamped at the end", 4, 1, 3, "m3", ""},
+		{"none requested", 1, 0, 0, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			preceding, following := c.SurroundingMessages(tt.i, tt.before, tt.after)
+			if got := ids(preceding); got != tt.wantBefore {
+				t.Errorf("preceding = %q, want %q", got, tt.wantBefore)
+			}
+			if got := ids(following); got != tt.wantAfter {
+				t.Errorf("following = %q, want %q", got, tt.wantAfter)
+			}
+		})
+	}
+}
+
 func stringPtr(s string) *string {
 	return &s
 }
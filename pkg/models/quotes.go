@@ -0,0 +1,180 @@
+package models
+
+import (
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	quoteTagRegex    = regexp.MustCompile(`<(/?)quote\b([^>]*)>`)
+	quoteAttrRegex   = regexp.MustCompile(`(\w+)="([^"]*)"`)
+	legacyQuoteRegex = regexp.MustCompile(`(?s)<legacyquote>.*?</legacyquote>`)
+)
+
+// Quote is a message quoted in a reply. Skype writes them as
+//
+//	<quote author="8:live:alice" authorname="Alice" timestamp="1704103200"
+//	  conversation="19:..." messageid="1704103200123">
+//	  <legacyquote>[1704103200] Alice: </legacyquote>original text<legacyquote>&lt;&lt;&lt; </legacyquote>
+//	</quote>reply text
+//
+// where the legacy quotes only serve clients that can't display quotes.
+type Quote struct {
+	Author         string    // id of the quoted sender
+	AuthorName     string    // name of the quoted sender, if given
+	Time           time.Time // when the quoted message was sent, zero when unknown
+	Text           string
+	ConversationId string
+	MessageId      string // id of the quoted message, which may be missing from the export
+}
+
+// HasQuotes reports whether the message quotes other messages
+func (m *SkypeMessage) HasQuotes() bool {
+	return strings.Contains(m.Content, "<quote")
+}
+
+// Quotes returns the messages quoted by the message, in order
+func (m *SkypeMessage) Quotes() []Quote {
+	if !m.HasQuotes() {
+		return nil
+	}
+
+	var quotes []Quote
+	for _, element := range findQuotes(m.Content) {
+		quote := Quote{Text: html.UnescapeString(quoteText(element.body))}
+		for _, attr := range quoteAttrRegex.FindAllStringSubmatch(element.attrs, -1) {
+			value := html.UnescapeString(attr[2])
+			switch attr[1] {
+			case "author":
+				quote.Author = value
+			case "authorname":
+				quote.AuthorName = value
+			case "timestamp":
+				if seconds, err := strconv.ParseInt(value, 10, 64); err == nil && seconds > 0 {
+					quote.Time = time.Unix(seconds, 0).UTC()
+				}
+			case "conversation":
+				quote.ConversationId = value
+			case "messageid":
+				quote.MessageId = value
+			}
+		}
+		quotes = append(quotes, quote)
+	}
+	return quotes
+}
+
+// AuthorDisplayName returns the name of the quoted sender, or their id
+func (q *Quote) AuthorDisplayName() string {
+	if q.AuthorName != "" {
+		return q.AuthorName
+	}
+	return q.Author
+}
+
+// QuotedMessage returns the message of the conversation that q quotes,
+// found by id or else by sender and time, or nil when it isn't there
+func (c *SkypeConversation) QuotedMessage(q *Quote) *SkypeMessage {
+	var fallback *SkypeMessage
+	for i := range c.MessageList {
+		msg := &c.MessageList[i]
+		if q.MessageId != "" && msg.OriginalId == q.MessageId {
+			return msg
+		}
+		if fallback == nil && q.Author != "" && !q.Time.IsZero() && msg.From == q.Author {
+			if t, err := msg.GetTimestamp(); err == nil && t.Unix() == q.Time.Unix() {
+				fallback = msg
+			}
+		}
+	}
+	return fallback
+}
+
+// quoteElement is a <quote> element of message content, which may quote
+// further messages itself
+type quoteElement struct {
+	start, end int    // byte span of the whole element
+	attrs      string // attributes of the opening tag
+	body       string // content between the tags
+}
+
+// findQuotes returns the outermost <quote> elements of content, matching
+// tags by depth so that the messages a quote quotes stay within it.
+// Unclosed quotes are left as they are.
+func findQuotes(content string) []quoteElement {
+	var (
+		quotes    []quoteElement
+		current   quoteElement
+		bodyStart int
+		depth     int
+	)
+	for _, loc := range quoteTagRegex.FindAllStringSubmatchIndex(content, -1) {
+		if loc[3] > loc[2] { // closing tag
+			if depth == 0 {
+				continue
+			}
+			depth--
+			if depth == 0 {
+				current.body = content[bodyStart:loc[0]]
+				current.end = loc[1]
+				quotes = append(quotes, current)
+			}
+			continue
+		}
+
+		if depth == 0 {
+			current = quoteElement{start: loc[0], attrs: content[loc[4]:loc[5]]}
+			bodyStart = loc[1]
+		}
+		depth++
+	}
+	return quotes
+}
+
+// replaceQuotes replaces the outermost <quote> elements of content with
+// what replace returns for them
+func replaceQuotes(content string, replace func(quote quoteElement) string) string {
+	quotes := findQuotes(content)
+	if len(quotes) == 0 {
+		return content
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, quote := range quotes {
+		b.WriteString(content[last:quote.start])
+		b.WriteString(replace(quote))
+		last = quote.end
+	}
+	b.WriteString(content[last:])
+	return b.String()
+}
+
+// removeQuotes drops the <quote> elements of content
+func removeQuotes(content string) string {
+	return replaceQuotes(content, func(quoteElement) string { return "" })
+}
+
+// quoteText returns the text of a quote body, still HTML escaped. Messages
+// it quotes in turn come first as "> " lines.
+func quoteText(body string) string {
+	body = legacyQuoteRegex.ReplaceAllString(body, "")
+	body = replaceQuotes(body, func(quote quoteElement) string {
+		return quotedLines(quote.body)
+	})
+	body = mentionRegex.ReplaceAllString(body, "@$2")
+	return strings.TrimSpace(htmlTagRegex.ReplaceAllString(body, ""))
+}
+
+// quotedLines renders a quote body as lines starting with "> ", the way
+// plain text mail quotes
+func quotedLines(body string) string {
+	lines := strings.Split(quoteText(body), "\n")
+	for i, line := range lines {
+		lines[i] = "> " + strings.TrimSpace(line)
+	}
+	return "\n" + strings.Join(lines, "\n") + "\n"
+}
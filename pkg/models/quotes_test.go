@@ -0,0 +1,93 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+const quotedReply = `<quote author="8:live:alice" authorname="Alice" timestamp="1704103200" conversation="19:team@thread.skype" messageid="m1">` +
+	`<legacyquote>[1704103200] Alice: </legacyquote>Are we <b>shipping</b> today?` + "\n" + `Tom &amp; Jerry asked<legacyquote>` + "\n\n" +
+	`&lt;&lt;&lt; </legacyquote></quote>Yes, at 5 &amp; not later`
+
+func TestSkypeMessage_Quotes(t *testing.T) {
+	msg := SkypeMessage{Content: quotedReply}
+
+	want := []Quote{{
+		Author:         "8:live:alice",
+		AuthorName:     "Alice",
+		Time:           time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC),
+		Text:           "Are we shipping today?\nTom & Jerry asked",
+		ConversationId: "19:team@thread.skype",
+		MessageId:      "m1",
+	}}
+	if got := msg.Quotes(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Quotes() = %+v, want %+v", got, want)
+	}
+	if got := msg.BodyText(); got != "Yes, at 5 & not later" {
+		t.Errorf("BodyText() = %q", got)
+	}
+	if got, want := msg.GetDisplayText(), "> Are we shipping today?\n> Tom & Jerry asked\nYes, at 5 & not later"; got != want {
+		t.Errorf("GetDisplayText() = %q, want %q", got, want)
+	}
+
+	plain := SkypeMessage{Content: "no <b>quote</b> here"}
+	if plain.HasQuotes() || plain.Quotes() != nil || plain.BodyText() != "no quote here" {
+		t.Errorf("unexpected quotes in %q", plain.Content)
+	}
+}
+
+func TestSkypeMessage_NestedQuotes(t *testing.T) {
+	msg := SkypeMessage{Content: `<quote author="8:live:bob" authorname="Bob" timestamp="1704103260">` +
+		`<legacyquote>[1704103260] Bob: </legacyquote>` +
+		`<quote author="8:live:alice" authorname="Alice" timestamp="1704103200">` +
+		`<legacyquote>[1704103200] Alice: </legacyquote>inner text<legacyquote>&lt;&lt;&lt; </legacyquote></quote>` +
+		`bob reply<legacyquote>&lt;&lt;&lt; </legacyquote></quote>my reply`}
+
+	quotes := msg.Quotes()
+	if len(quotes) != 1 || quotes[0].AuthorName != "Bob" || quotes[0].Text != "> inner text\nbob reply" {
+		t.Errorf("Quotes() = %+v", quotes)
+	}
+	if got := msg.BodyText(); got != "my reply" {
+		t.Errorf("BodyText() = %q", got)
+	}
+	if got, want := msg.GetDisplayText(), "> > inner text\n> bob reply\nmy reply"; got != want {
+		t.Errorf("GetDisplayText() = %q, want %q", got, want)
+	}
+
+	// An unclosed quote is left as text rather than swallowing the reply
+	broken := SkypeMessage{Content: `<quote author="8:live:bob">half`}
+	if broken.Quotes() != nil || broken.BodyText() != "half" {
+		t.Errorf("unexpected handling of an unclosed quote: %+v, %q", broken.Quotes(), broken.BodyText())
+	}
+}
+
+func TestSkypeConversation_QuotedMessage(t *testing.T) {
+	conv := SkypeConversation{MessageList: []SkypeMessage{
+		{OriginalId: "m1", From: "8:live:alice", Timestamp: "2024-01-01T10:00:00.250Z"},
+		{OriginalId: "m2", From: "8:live:bob", Timestamp: "2024-01-01T10:00:00Z"},
+	}}
+	at := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		quote Quote
+		want  string
+	}{
+		{"by id", Quote{MessageId: "m2", Author: "8:live:alice", Time: at}, "m2"},
+		{"by sender and time", Quote{MessageId: "gone", Author: "8:live:alice", Time: at}, "m1"},
+		{"missing", Quote{MessageId: "gone", Author: "8:live:carol", Time: at}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ""
+			if msg := conv.QuotedMessage(&tt.quote); msg != nil {
+				got = msg.OriginalId
+			}
+			if got != tt.want {
+				t.Errorf("QuotedMessage() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
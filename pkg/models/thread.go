@@ -0,0 +1,272 @@
+package models
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Thread event types, from the root element of ThreadActivity payloads
+const (
+	ThreadMemberAdded    = "addmember"
+	ThreadMemberRemoved  = "deletemember"
+	ThreadTopicUpdated   = "topicupdate"
+	ThreadPictureUpdated = "pictureupdate"
+	ThreadRoleUpdated    = "roleupdate"
+)
+
+// ThreadEvent is the payload of a ThreadActivity message, such as
+//
+//	<addmember>
+//	  <eventtime>1704240120000</eventtime>
+//	  <initiator>8:live:manager</initiator>
+//	  <target>8:live:alice</target>
+//	</addmember>
+type ThreadEvent struct {
+	Type      string    // one of the Thread* types, or the element name as written
+	Time      time.Time // message time, or <eventtime> when invalid
+	Initiator string
+	Targets   []ThreadTarget
+	Value     string // new topic or picture
+}
+
+// ThreadTarget is a member affected by a thread event
+type ThreadTarget struct {
+	Id   string
+	Name string // friendly name given by the event, if any
+	Role string // new role of a roleupdate
+}
+
+// threadEventXML mirrors the XML of a thread event
+type threadEventXML struct {
+	XMLName   xml.Name
+	EventTime string `xml:"eventtime"`
+	Initiator string `xml:"initiator"`
+	Value     string `xml:"value"`
+	Targets   []struct {
+		Text string `xml:",chardata"`
+		Id   string `xml:"id"`
+		Role string `xml:"role"`
+	} `xml:"target"`
+	Details []struct {
+		Id           string `xml:"id"`
+		FriendlyName string `xml:"friendlyname"`
+	} `xml:"detailedtargetinfo"`
+}
+
+// IsThreadActivity reports whether the message records a change to a group
+func (m *SkypeMessage) IsThreadActivity() bool {
+	return strings.Contains(m.MessageType, "ThreadActivity")
+}
+
+// ParseThreadEvent parses the XML payload of a ThreadActivity message
+func (m *SkypeMessage) ParseThreadEvent() (*ThreadEvent, error) {
+	if !m.IsThreadActivity() {
+		return nil, fmt.Errorf("not a thread activity: %s", m.MessageType)
+	}
+	if !strings.HasPrefix(strings.TrimSpace(m.Content), "<") {
+		return nil, fmt.Errorf("thread activity without an event")
+	}
+
+	decoder := xml.NewDecoder(strings.NewReader(m.Content))
+	decoder.Strict = false
+	decoder.Entity = xml.HTMLEntity
+
+	var raw threadEventXML
+	if err := decoder.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("failed to parse thread activity: %w", err)
+	}
+
+	event := &ThreadEvent{
+		Type:      strings.ToLower(raw.XMLName.Local),
+		Initiator: strings.TrimSpace(raw.Initiator),
+		Value:     strings.TrimSpace(raw.Value),
+	}
+	// Events are placed at the message time like every other message,
+	// their own <eventtime> only standing in for an invalid one
+	if t, err := m.GetTimestamp(); err == nil {
+		event.Time = t
+	} else if millis, err := strconv.ParseInt(strings.TrimSpace(raw.EventTime), 10, 64); err == nil && millis > 0 {
+		event.Time = time.UnixMilli(millis).UTC()
+	}
+
+	names := make(map[string]string, len(raw.Details))
+	for _, detail := range raw.Details {
+		names[strings.TrimSpace(detail.Id)] = strings.TrimSpace(detail.FriendlyName)
+	}
+	for _, target := range raw.Targets {
+		id := strings.TrimSpace(target.Id)
+		if id == "" {
+			id = strings.TrimSpace(target.Text)
+		}
+		if id == "" {
+			continue
+		}
+		event.Targets = append(event.Targets, ThreadTarget{Id: id, Name: names[id], Role: strings.TrimSpace(target.Role)})
+	}
+	return event, nil
+}
+
+// Describe summarizes the event in a sentence, naming members after names
+// and by their id when missing from it
+func (e *ThreadEvent) Describe(names map[string]string) string {
+	name := func(id string) string {
+		if n, ok := names[id]; ok {
+			return n
+		}
+		return id
+	}
+	initiator := "Someone"
+	if e.Initiator != "" {
+		initiator = name(e.Initiator)
+	}
+	targets := make([]string, len(e.Targets))
+	for i, target := range e.Targets {
+		if target.Name != "" {
+			targets[i] = target.Name
+		} else {
+			targets[i] = name(target.Id)
+		}
+	}
+	bySelf := len(e.Targets) == 1 && e.Targets[0].Id == e.Initiator
+
+	switch e.Type {
+	case ThreadMemberAdded:
+		if bySelf {
+			return targets[0] + " joined"
+		}
+		return initiator + " added " + strings.Join(targets, ", ")
+	case ThreadMemberRemoved:
+		if bySelf {
+			return targets[0] + " left"
+		}
+		return initiator + " removed " + strings.Join(targets, ", ")
+	case ThreadTopicUpdated:
+		if e.Value == "" {
+			return initiator + " cleared the topic"
+		}
+		return fmt.Sprintf("%s changed the topic to %q", initiator, e.Value)
+	case ThreadPictureUpdated:
+		return initiator + " changed the group picture"
+	case ThreadRoleUpdated:
+		changes := make([]string, len(e.Targets))
+		for i, target := range e.Targets {
+			changes[i] = targets[i]
+			if target.Role != "" {
+				changes[i] += " " + strings.ToLower(target.Role)
+			}
+		}
+		return initiator + " made " + strings.Join(changes, ", ")
+	default:
+		return fmt.Sprintf("%s updated the conversation (%s)", initiator, e.Type)
+	}
+}
+
+// MemberNames maps the ids of those who wrote in the conversation to the
+// name they last wrote under
+func (c *SkypeConversation) MemberNames() map[string]string {
+	names := make(map[string]string)
+	for i := range c.MessageList {
+		msg := &c.MessageList[i]
+		if msg.IsSystemMessage() || msg.DisplayName == nil || *msg.DisplayName == "" {
+			continue
+		}
+		names[msg.From] = *msg.DisplayName
+	}
+	return names
+}
+
+// ThreadEvents returns the thread events of the conversation in
+// chronological order, skipping those that can't be parsed
+func (c *SkypeConversation) ThreadEvents() []ThreadEvent {
+	var events []ThreadEvent
+	for i := range c.MessageList {
+		msg := &c.MessageList[i]
+		if !msg.IsThreadActivity() {
+			continue
+		}
+		if event, err := msg.ParseThreadEvent(); err == nil {
+			events = append(events, *event)
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Time.Before(events[j].Time)
+	})
+	return events
+}
+
+// Membership is a period during which someone belonged to a group
+type Membership struct {
+	Id        string
+	Added     time.Time // zero when already a member before the first event
+	AddedBy   string
+	Removed   time.Time // zero while still a member
+	RemovedBy string
+}
+
+// MembershipHistory reconstructs who belonged to the group and when, from
+// its thread events and current member list. Members are ordered by when
+// they joined; someone who left and came back has a membership per stay.
+func (c *SkypeConversation) MembershipHistory() []Membership {
+	var history []Membership
+	current := make(map[string]int) // member id to its open membership
+
+	for _, event := range c.ThreadEvents() {
+		for _, target := range event.Targets {
+			switch event.Type {
+			case ThreadMemberAdded:
+				if _, ok := current[target.Id]; !ok {
+					current[target.Id] = len(history)
+					history = append(history, Membership{Id: target.Id, Added: event.Time, AddedBy: event.Initiator})
+				}
+			case ThreadMemberRemoved:
+				i, ok := current[target.Id]
+				if !ok {
+					// A member since before the export began
+					i = len(history)
+					history = append(history, Membership{Id: target.Id})
+				}
+				history[i].Removed = event.Time
+				history[i].RemovedBy = event.Initiator
+				delete(current, target.Id)
+			}
+		}
+	}
+
+	// Members listed by the group but never added within the export were
+	// there from the start
+	var founders []Membership
+	seen := make(map[string]bool, len(history))
+	for _, membership := range history {
+		seen[membership.Id] = true
+	}
+	for _, id := range c.threadMembers() {
+		if !seen[id] {
+			seen[id] = true
+			founders = append(founders, Membership{Id: id})
+		}
+	}
+	history = append(founders, history...)
+
+	sort.SliceStable(history, func(i, j int) bool {
+		return history[i].Added.Before(history[j].Added)
+	})
+	return history
+}
+
+// threadMembers returns the member ids listed in the thread properties,
+// which the export stores as a JSON encoded array
+func (c *SkypeConversation) threadMembers() []string {
+	if c.ThreadProperties == nil || c.ThreadProperties.Members == nil {
+		return nil
+	}
+	var members []string
+	if err := json.Unmarshal([]byte(*c.ThreadProperties.Members), &members); err != nil {
+		return nil
+	}
+	return members
+}
@@ -0,0 +1,108 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func threadMessage(timestamp, content string) SkypeMessage {
+	return SkypeMessage{MessageType: "ThreadActivity/AddMember", Timestamp: timestamp, Content: content}
+}
+
+func TestSkypeMessage_ParseThreadEvent(t *testing.T) {
+	at := time.Date(2024, 1, 3, 9, 2, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		msg     SkypeMessage
+		want    *ThreadEvent
+		wantErr bool
+	}{
+		{
+			name: "add member",
+			msg: SkypeMessage{MessageType: "Control/ThreadActivity", Timestamp: "2024-01-03T09:02:00Z",
+				Content: "<addmember><eventtime>1704240120000</eventtime><initiator>8:live:manager</initiator><target>8:live:alice</target></addmember>"},
+			want: &ThreadEvent{Type: ThreadMemberAdded, Time: at, Initiator: "8:live:manager", Targets: []ThreadTarget{{Id: "8:live:alice"}}},
+		},
+		{
+			name: "detailed targets",
+			msg: threadMessage("invalid", "<addmember><eventtime>1704272520000</eventtime><initiator>8:a</initiator>"+
+				"<detailedtargetinfo><id>8:b</id><friendlyname>Bob &amp; Co</friendlyname></detailedtargetinfo><target>8:b</target><target>8:c</target></addmember>"),
+			want: &ThreadEvent{Type: ThreadMemberAdded, Time: at, Initiator: "8:a", Targets: []ThreadTarget{{Id: "8:b", Name: "Bob & Co"}, {Id: "8:c"}}},
+		},
+		{
+			name: "role update",
+			msg:  threadMessage("2024-01-03T09:02:00Z", "<roleupdate><initiator>8:a</initiator><target><id>8:b</id><role>Admin</role></target></roleupdate>"),
+			want: &ThreadEvent{Type: ThreadRoleUpdated, Time: at, Initiator: "8:a", Targets: []ThreadTarget{{Id: "8:b", Role: "Admin"}}},
+		},
+		{
+			name: "topic update",
+			msg:  threadMessage("2024-01-03T09:02:00Z", "<topicupdate><initiator>8:a</initiator><value>Sprint&nbsp;Retro</value></topicupdate>"),
+			want: &ThreadEvent{Type: ThreadTopicUpdated, Time: at, Initiator: "8:a", Value: "Sprint Retro"},
+		},
+		{name: "plain text", msg: threadMessage("2024-01-03T09:02:00Z", "joined"), wantErr: true},
+		{name: "not a thread activity", msg: SkypeMessage{MessageType: "RichText", Content: "<addmember></addmember>"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.msg.ParseThreadEvent()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseThreadEvent() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseThreadEvent() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestThreadEvent_Describe(t *testing.T) {
+	names := map[string]string{"8:a": "Alice", "8:b": "Bob"}
+	tests := []struct {
+		event ThreadEvent
+		want  string
+	}{
+		{ThreadEvent{Type: ThreadMemberAdded, Initiator: "8:a", Targets: []ThreadTarget{{Id: "8:b"}, {Id: "8:c", Name: "Carol"}}}, "Alice added Bob, Carol"},
+		{ThreadEvent{Type: ThreadMemberAdded, Initiator: "8:b", Targets: []ThreadTarget{{Id: "8:b"}}}, "Bob joined"},
+		{ThreadEvent{Type: ThreadMemberRemoved, Initiator: "8:a", Targets: []ThreadTarget{{Id: "8:x"}}}, "Alice removed 8:x"},
+		{ThreadEvent{Type: ThreadMemberRemoved, Initiator: "8:b", Targets: []ThreadTarget{{Id: "8:b"}}}, "Bob left"},
+		{ThreadEvent{Type: ThreadTopicUpdated, Initiator: "8:a", Value: "Retro"}, `Alice changed the topic to "Retro"`},
+		{ThreadEvent{Type: ThreadTopicUpdated}, "Someone cleared the topic"},
+		{ThreadEvent{Type: ThreadPictureUpdated, Initiator: "8:b"}, "Bob changed the group picture"},
+		{ThreadEvent{Type: ThreadRoleUpdated, Initiator: "8:a", Targets: []ThreadTarget{{Id: "8:b", Role: "Admin"}}}, "Alice made Bob admin"},
+		{ThreadEvent{Type: "joiningenabledupdate", Initiator: "8:a"}, "Alice updated the conversation (joiningenabledupdate)"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.event.Describe(names); got != tt.want {
+			t.Errorf("Describe() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestSkypeConversation_MembershipHistory(t *testing.T) {
+	members := `["8:founder","8:a","8:b"]`
+	conv := SkypeConversation{
+		ThreadProperties: &ThreadProperties{Members: &members},
+		MessageList: []SkypeMessage{
+			threadMessage("2024-01-02T10:00:00Z", "<deletemember><initiator>8:founder</initiator><target>8:a</target></deletemember>"),
+			threadMessage("2024-01-01T10:00:00Z", "<addmember><initiator>8:founder</initiator><target>8:a</target></addmember>"),
+			threadMessage("2024-01-03T10:00:00Z", "<addmember><initiator>8:a</initiator><target>8:a</target></addmember>"),
+			threadMessage("2024-01-04T10:00:00Z", "<deletemember><initiator>8:old</initiator><target>8:old</target></deletemember>"),
+			threadMessage("2024-01-05T10:00:00Z", "<addmember><initiator>8:a</initiator><target>8:b</target></addmember>"),
+		},
+	}
+
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 10, 0, 0, 0, time.UTC) }
+	want := []Membership{
+		{Id: "8:founder"},
+		{Id: "8:old", Removed: day(4), RemovedBy: "8:old"},
+		{Id: "8:a", Added: day(1), AddedBy: "8:founder", Removed: day(2), RemovedBy: "8:founder"},
+		{Id: "8:a", Added: day(3), AddedBy: "8:a"},
+		{Id: "8:b", Added: day(5), AddedBy: "8:a"},
+	}
+	if got := conv.MembershipHistory(); !reflect.DeepEqual(got, want) {
+		t.Errorf("MembershipHistory() = %+v, want %+v", got, want)
+	}
+}
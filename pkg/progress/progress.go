@@ -0,0 +1,46 @@
+// Package progress reports what long-running operations on an export are
+// doing, so that library packages never print on their own
+package progress
+
+// LoadSummary describes a fully read export
+type LoadSummary struct {
+	UserId        string
+	ExportDate    string
+	Conversations int
+	Messages      int
+}
+
+// Reporter receives progress events. Search workers may report
+// concurrently, so implementations must be safe for concurrent use.
+type Reporter interface {
+	// LoadStarted is called once an export has been opened
+	LoadStarted(source string, size int64)
+	// LoadProgress reports how much of the export has been parsed
+	LoadProgress(bytesRead, size int64, conversations int)
+	// LoadFinished is called after the whole export has been read
+	LoadFinished(summary LoadSummary)
+	// SearchProgress reports done out of total units searched, where unit
+	// is "messages", "bytes" or "candidates"
+	SearchProgress(done, total int64, unit string)
+	// SearchFinished is called when a search stops, whatever the outcome
+	SearchFinished()
+}
+
+// Silent discards every event
+var Silent Reporter = silent{}
+
+type silent struct{}
+
+func (silent) LoadStarted(string, int64)           {}
+func (silent) LoadProgress(int64, int64, int)      {}
+func (silent) LoadFinished(LoadSummary)            {}
+func (silent) SearchProgress(int64, int64, string) {}
+func (silent) SearchFinished()                     {}
+
+// Or returns r, or Silent when r is nil
+func Or(r Reporter) Reporter {
+	if r == nil {
+		return Silent
+	}
+	return r
+}
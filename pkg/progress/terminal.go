@@ -0,0 +1,209 @@
+package progress
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+	"golang.org/x/term"
+)
+
+const (
+	// barWidth is the number of cells of the load progress bar
+	barWidth = 30
+	// redrawInterval throttles progress redrawn in place on a terminal
+	redrawInterval = 100 * time.Millisecond
+	// logInterval throttles progress written as log lines
+	logInterval = 5 * time.Second
+)
+
+// Terminal renders events as colored text. On a terminal, progress is a
+// single line redrawn in place; elsewhere, such as when redirected to a
+// file, it is written as periodic log lines.
+type Terminal struct {
+	w           io.Writer
+	interactive bool
+	now         func() time.Time
+
+	mu          sync.Mutex
+	lastUpdate  time.Time
+	loadStart   time.Time
+	searchStart time.Time
+	lineDrawn   bool
+}
+
+// NewTerminal creates a reporter writing to w, usually os.Stderr so that
+// progress never mixes with the results on stdout
+func NewTerminal(w io.Writer) *Terminal {
+	interactive := false
+	if f, ok := w.(*os.File); ok {
+		interactive = term.IsTerminal(int(f.Fd()))
+	}
+	return &Terminal{w: w, interactive: interactive, now: time.Now}
+}
+
+// LoadStarted prints where the export is read from
+func (t *Terminal) LoadStarted(source string, size int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.clearLine()
+	fmt.Fprintln(t.w)
+	color.New(color.FgCyan).Fprintf(t.w, "Loading Skype history from: %s\n", source)
+	color.New(color.FgYellow).Fprintf(t.w, "File size: %s\n", formatMB(size))
+	t.loadStart = t.now()
+	t.lastUpdate = t.loadStart
+}
+
+// LoadProgress shows how much of the export has been read, how fast and
+// how long the rest should take
+func (t *Terminal) LoadProgress(bytesRead, size int64, conversations int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.due() {
+		return
+	}
+
+	elapsed := t.now().Sub(t.loadStart)
+	status := formatMB(bytesRead)
+	if size > 0 {
+		status = fmt.Sprintf("%.1f%% %s/%s", percent(bytesRead, size), trimMB(bytesRead), formatMB(size))
+	}
+	if rate := throughput(bytesRead, elapsed); rate > 0 {
+		status += fmt.Sprintf(", %.1f MB/s", rate/(1024*1024))
+		if size > bytesRead {
+			status += ", ETA " + formatETA(time.Duration(float64(size-bytesRead)/rate*float64(time.Second)))
+		}
+	}
+	if conversations > 0 {
+		status += fmt.Sprintf(", %d conversations", conversations)
+	}
+
+	if t.interactive {
+		fmt.Fprintf(t.w, "\r%s %s   ", progressBar(bytesRead, size), status)
+		t.lineDrawn = true
+	} else {
+		fmt.Fprintf(t.w, "Loading: %s\n", status)
+	}
+}
+
+// LoadFinished prints a summary of the export
+func (t *Terminal) LoadFinished(summary LoadSummary) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.clearLine()
+	fmt.Fprintln(t.w)
+	color.New(color.FgGreen, color.Bold).Fprintf(t.w, "✓ Successfully loaded Skype history in %s\n", formatETA(t.now().Sub(t.loadStart)))
+	fmt.Fprintf(t.w, "  User ID: %s\n", summary.UserId)
+	fmt.Fprintf(t.w, "  Export Date: %s\n", summary.ExportDate)
+	fmt.Fprintf(t.w, "  Conversations: %d\n", summary.Conversations)
+	fmt.Fprintf(t.w, "  Total Messages: %d\n", summary.Messages)
+	fmt.Fprintln(t.w)
+}
+
+// SearchProgress shows how much of the search is done
+func (t *Terminal) SearchProgress(done, total int64, unit string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.searchStart.IsZero() {
+		t.searchStart = t.now()
+	}
+	if !t.due() {
+		return
+	}
+
+	status := fmt.Sprintf("%.1f%% (%d/%d %s) - %.1fs",
+		percent(done, total), done, total, unit, t.now().Sub(t.searchStart).Seconds())
+	if t.interactive {
+		fmt.Fprint(t.w, "\r")
+		color.New(color.FgYellow).Fprintf(t.w, "Searching... %s", status)
+		t.lineDrawn = true
+	} else {
+		fmt.Fprintf(t.w, "Searching: %s\n", status)
+	}
+}
+
+// SearchFinished clears the search line
+func (t *Terminal) SearchFinished() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.clearLine()
+	t.searchStart = time.Time{}
+}
+
+// due reports whether enough time has passed since the last progress
+// update to show another one; the caller holds mu
+func (t *Terminal) due() bool {
+	interval := logInterval
+	if t.interactive {
+		interval = redrawInterval
+	}
+	now := t.now()
+	if now.Sub(t.lastUpdate) < interval {
+		return false
+	}
+	t.lastUpdate = now
+	return true
+}
+
+// clearLine erases a progress line left on screen; the caller holds mu
+func (t *Terminal) clearLine() {
+	if t.lineDrawn {
+		fmt.Fprintf(t.w, "\r%s\r", strings.Repeat(" ", 100))
+		t.lineDrawn = false
+	}
+}
+
+// progressBar draws done out of total as a bar of barWidth cells
+func progressBar(done, total int64) string {
+	filled := 0
+	if total > 0 {
+		filled = int(min(done, total) * barWidth / total)
+	}
+	if filled == barWidth {
+		return "[" + strings.Repeat("=", barWidth) + "]"
+	}
+	return "[" + strings.Repeat("=", filled) + ">" + strings.Repeat(" ", barWidth-filled-1) + "]"
+}
+
+// percent returns done out of total as a percentage
+func percent(done, total int64) float64 {
+	if total <= 0 {
+		return 0
+	}
+	return float64(done) / float64(total) * 100
+}
+
+// throughput returns the bytes read per second
+func throughput(bytesRead int64, elapsed time.Duration) float64 {
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(bytesRead) / elapsed.Seconds()
+}
+
+// formatMB formats a byte count in megabytes
+func formatMB(n int64) string {
+	return trimMB(n) + " MB"
+}
+
+// trimMB formats a byte count in megabytes without the unit
+func trimMB(n int64) string {
+	return fmt.Sprintf("%.2f", float64(n)/(1024*1024))
+}
+
+// formatETA formats a duration to the second, or in tenths below that
+func formatETA(d time.Duration) string {
+	if d < time.Second {
+		return d.Round(100 * time.Millisecond).String()
+	}
+	return d.Round(time.Second).String()
+}
@@ -0,0 +1,142 @@
+package progress
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// newTestTerminal returns a terminal on buf driven by a clock advanced by hand
+func newTestTerminal(buf *bytes.Buffer, interactive bool) (*Terminal, func(time.Duration)) {
+	clock := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	terminal := NewTerminal(buf)
+	terminal.interactive = interactive
+	terminal.now = func() time.Time { return clock }
+	return terminal, func(d time.Duration) { clock = clock.Add(d) }
+}
+
+func TestTerminalInteractive(t *testing.T) {
+	color.NoColor = true
+
+	var buf bytes.Buffer
+	terminal, advance := newTestTerminal(&buf, true)
+
+	terminal.LoadStarted("messages.json", 100<<20)
+	if !strings.Contains(buf.String(), "Loading Skype history from: messages.json") || !strings.Contains(buf.String(), "File size: 100.00 MB") {
+		t.Errorf("unexpected load banner: %q", buf.String())
+	}
+
+	// Progress right after the banner is throttled
+	buf.Reset()
+	terminal.LoadProgress(10<<20, 100<<20, 1)
+	if buf.Len() != 0 {
+		t.Errorf("expected throttled progress, got %q", buf.String())
+	}
+
+	advance(2 * time.Second)
+	terminal.LoadProgress(50<<20, 100<<20, 3)
+	want := "\r[===============>              ] 50.0% 50.00/100.00 MB, 25.0 MB/s, ETA 2s, 3 conversations   "
+	if buf.String() != want {
+		t.Errorf("expected %q, got %q", want, buf.String())
+	}
+
+	buf.Reset()
+	advance(2 * time.Second)
+	terminal.LoadFinished(LoadSummary{UserId: "me", Conversations: 3, Messages: 42})
+	if !strings.HasPrefix(buf.String(), "\r"+strings.Repeat(" ", 100)+"\r") {
+		t.Errorf("expected the bar to be cleared first, got %q", buf.String())
+	}
+	for _, want := range []string{"loaded Skype history in 4s", "User ID: me", "Conversations: 3", "Total Messages: 42"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("expected %q in summary %q", want, buf.String())
+		}
+	}
+
+	buf.Reset()
+	advance(time.Second)
+	terminal.SearchProgress(25, 100, "messages")
+	if !strings.Contains(buf.String(), "\rSearching... 25.0% (25/100 messages)") {
+		t.Errorf("unexpected search progress: %q", buf.String())
+	}
+
+	buf.Reset()
+	terminal.SearchFinished()
+	if strings.TrimSpace(buf.String()) != "" || buf.Len() == 0 {
+		t.Errorf("expected the progress line to be cleared, got %q", buf.String())
+	}
+
+	// Nothing left to clear
+	buf.Reset()
+	terminal.SearchFinished()
+	if buf.Len() != 0 {
+		t.Errorf("expected no output, got %q", buf.String())
+	}
+}
+
+func TestTerminalLogLines(t *testing.T) {
+	color.NoColor = true
+
+	var buf bytes.Buffer
+	terminal, advance := newTestTerminal(&buf, false)
+
+	terminal.LoadStarted("messages.json", 100<<20)
+	buf.Reset()
+
+	// Log lines are written less often than the bar is redrawn
+	advance(time.Second)
+	terminal.LoadProgress(10<<20, 100<<20, 0)
+	if buf.Len() != 0 {
+		t.Errorf("expected throttled progress, got %q", buf.String())
+	}
+
+	advance(4 * time.Second)
+	terminal.LoadProgress(20<<20, 100<<20, 0)
+	want := "Loading: 20.0% 20.00/100.00 MB, 4.0 MB/s, ETA 20s\n"
+	if buf.String() != want {
+		t.Errorf("expected %q, got %q", want, buf.String())
+	}
+
+	buf.Reset()
+	terminal.LoadFinished(LoadSummary{})
+	if strings.Contains(buf.String(), "\r") {
+		t.Errorf("expected no carriage returns, got %q", buf.String())
+	}
+
+	buf.Reset()
+	advance(5 * time.Second)
+	terminal.SearchProgress(1, 4, "candidates")
+	if buf.String() != "Searching: 25.0% (1/4 candidates) - 0.0s\n" {
+		t.Errorf("unexpected search progress: %q", buf.String())
+	}
+}
+
+func TestProgressBar(t *testing.T) {
+	tests := []struct {
+		done, total int64
+		want        string
+	}{
+		{0, 100, "[>" + strings.Repeat(" ", barWidth-1) + "]"},
+		{100, 100, "[" + strings.Repeat("=", barWidth) + "]"},
+		{150, 100, "[" + strings.Repeat("=", barWidth) + "]"},
+		{10, 0, "[>" + strings.Repeat(" ", barWidth-1) + "]"},
+	}
+
+	for _, tt := range tests {
+		if got := progressBar(tt.done, tt.total); got != tt.want {
+			t.Errorf("progressBar(%d, %d) = %q, want %q", tt.done, tt.total, got, tt.want)
+		}
+	}
+}
+
+func TestOr(t *testing.T) {
+	if Or(nil) != Silent {
+		t.Error("expected nil to fall back to Silent")
+	}
+	terminal := NewTerminal(&bytes.Buffer{})
+	if Or(terminal) != terminal {
+		t.Error("expected a reporter to be kept")
+	}
+}
@@ -0,0 +1,211 @@
+package search
+
+import (
+	"container/list"
+	"encoding/gob"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/beckxie/skype-history-viewer-cli/pkg/models"
+	"github.com/beckxie/skype-history-viewer-cli/pkg/viewer"
+)
+
+// DefaultCacheSize bounds the approximate memory, in bytes, held by the
+// cached results of a SearchManager
+const DefaultCacheSize = 32 << 20
+
+// cacheFormatVersion is bumped whenever the persisted layout or the content
+// of results changes, such as a new SearchResult field, a change to the
+// text GetDisplayText renders or to which messages a query matches, so that
+// upgrades don't serve stale results
+const cacheFormatVersion = 5
+
+// resultCache keeps search results in least recently used order and evicts
+// the oldest once their total size exceeds maxSize
+type resultCache struct {
+	mu      sync.Mutex
+	maxSize int64
+	size    int64
+	order   *list.List // of *cacheEntry, most recently used first
+	entries map[string]*list.Element
+}
+
+// cacheEntry is one cached search, also the persisted form of it
+type cacheEntry struct {
+	Key     string
+	Results []viewer.SearchResult
+	size    int64
+}
+
+func newResultCache(maxSize int64) *resultCache {
+	return &resultCache{
+		maxSize: maxSize,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// get returns the results cached under key and marks them recently used
+func (c *resultCache) get(key string) ([]viewer.SearchResult, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	return element.Value.(*cacheEntry).Results, true
+}
+
+// put caches results under key, evicting the least recently used entries
+// to stay within maxSize. Results larger than the whole cache are skipped.
+func (c *resultCache) put(key string, results []viewer.SearchResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.remove(key)
+
+	entry := &cacheEntry{Key: key, Results: results, size: int64(len(key)) + resultsSize(results)}
+	if entry.size > c.maxSize {
+		return
+	}
+	c.entries[key] = c.order.PushFront(entry)
+	c.size += entry.size
+
+	for c.size > c.maxSize {
+		c.remove(c.order.Back().Value.(*cacheEntry).Key)
+	}
+}
+
+// remove drops key from the cache; the caller holds mu
+func (c *resultCache) remove(key string) {
+	element, ok := c.entries[key]
+	if !ok {
+		return
+	}
+	c.order.Remove(element)
+	delete(c.entries, key)
+	c.size -= element.Value.(*cacheEntry).size
+}
+
+// len returns the number of cached searches
+func (c *resultCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// clear empties the cache
+func (c *resultCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.order.Init()
+	c.entries = make(map[string]*list.Element)
+	c.size = 0
+}
+
+// resultsSize estimates the memory held by results from their text fields
+// plus a fixed overhead per result
+func resultsSize(results []viewer.SearchResult) int64 {
+	const overhead = 256
+
+	var size int64
+	for i := range results {
+		r := &results[i]
+		size += overhead + int64(len(r.ConversationId)+len(r.ConversationName)+len(r.MatchContext)+len(r.MatchType)+len(r.MatchedVariant))
+		size += messageSize(&r.Message)
+		for j := range r.Before {
+			size += overhead + messageSize(&r.Before[j])
+		}
+		for j := range r.After {
+			size += overhead + messageSize(&r.After[j])
+		}
+	}
+	return size
+}
+
+// messageSize estimates the memory held by the text of a message
+func messageSize(msg *models.SkypeMessage) int64 {
+	size := int64(len(msg.OriginalId) + len(msg.Content) + len(msg.Timestamp) + len(msg.MessageType) + len(msg.From) + len(msg.ConversationId))
+	if msg.DisplayName != nil {
+		size += int64(len(*msg.DisplayName))
+	}
+	for _, ref := range msg.AmsReferences {
+		size += int64(len(ref))
+	}
+	if msg.Properties != nil && msg.Properties.UrlPreviews != nil {
+		size += int64(len(*msg.Properties.UrlPreviews))
+	}
+	return size
+}
+
+// cacheFile is the persisted form of a cache, tied to one build of an export
+type cacheFile struct {
+	Version int
+	Key     string
+	Entries []cacheEntry // least recently used first
+}
+
+// LoadCache fills the cache with the results persisted at path. Nothing is
+// loaded when the file is missing, unreadable or was saved for another
+// exportKey, so that results never outlive the export they came from.
+func (sm *SearchManager) LoadCache(path, exportKey string) error {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open search cache: %w", err)
+	}
+	defer file.Close()
+
+	var persisted cacheFile
+	if err := gob.NewDecoder(file).Decode(&persisted); err != nil {
+		return nil
+	}
+	if persisted.Version != cacheFormatVersion || persisted.Key != exportKey {
+		return nil
+	}
+
+	for _, entry := range persisted.Entries {
+		sm.cache.put(entry.Key, entry.Results)
+	}
+	return nil
+}
+
+// SaveCache persists the cache at path, tagged with exportKey
+func (sm *SearchManager) SaveCache(path, exportKey string) error {
+	sm.cache.mu.Lock()
+	persisted := cacheFile{Version: cacheFormatVersion, Key: exportKey}
+	for element := sm.cache.order.Back(); element != nil; element = element.Prev() {
+		persisted.Entries = append(persisted.Entries, *element.Value.(*cacheEntry))
+	}
+	sm.cache.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	// Write to a temporary file first so that readers never see a partial one
+	tmpFile, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed to save search cache: %w", err)
+	}
+	defer os.Remove(tmpFile.Name())
+
+	if err := gob.NewEncoder(tmpFile).Encode(&persisted); err != nil {
+		tmpFile.Close()
+		return fmt.Errorf("failed to save search cache: %w", err)
+	}
+	if err := tmpFile.Close(); err != nil {
+		return fmt.Errorf("failed to save search cache: %w", err)
+	}
+	if err := os.Rename(tmpFile.Name(), path); err != nil {
+		return fmt.Errorf("failed to save search cache: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package search
+
+import (
+	"context"
+	"encoding/gob"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/beckxie/skype-history-viewer-cli/pkg/models"
+	"github.com/beckxie/skype-history-viewer-cli/pkg/utils"
+	"github.com/beckxie/skype-history-viewer-cli/pkg/viewer"
+)
+
+func TestResultCache_LeastRecentlyUsed(t *testing.T) {
+	results := []viewer.SearchResult{{MatchContext: "abc"}}
+	c := newResultCache(3 * (int64(len("a")) + resultsSize(results)))
+
+	c.put("a", results)
+	c.put("b", results)
+	c.put("c", results)
+
+	// Reading a makes b the least recently used
+	if _, ok := c.get("a"); !ok {
+		t.Fatal("expected a to be cached")
+	}
+	c.put("d", results)
+
+	if _, ok := c.get("b"); ok {
+		t.Error("expected b to be evicted")
+	}
+	for _, key := range []string{"a", "c", "d"} {
+		if _, ok := c.get(key); !ok {
+			t.Errorf("expected %s to be cached", key)
+		}
+	}
+
+	// Replacing an entry doesn't count it twice
+	c.put("d", results)
+	if c.len() != 3 {
+		t.Errorf("expected 3 entries, got %d", c.len())
+	}
+
+	// Results larger than the whole cache are not kept
+	c.put("huge", []viewer.SearchResult{{MatchContext: strings.Repeat("x", 10000)}})
+	if _, ok := c.get("huge"); ok {
+		t.Error("expected oversized results to be skipped")
+	}
+	if c.len() != 3 {
+		t.Errorf("expected oversized results to leave the cache alone, got %d entries", c.len())
+	}
+}
+
+func TestSearchManager_PersistentCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "search.cache")
+	jsonContent := `{"userId": "u", "conversations": [
+		{"id": "a", "displayName": "First", "MessageList": [
+			{"id": "m1", "content": "apple pie", "messagetype": "Text", "originalarrivaltime": "2024-01-01T10:00:00Z"}
+		]}
+	]}`
+	options := SearchOptions{Query: "apple", SearchInContent: true}
+
+	sm := NewSearchManager(nil)
+	results, err := sm.SearchStream(context.Background(), utils.NewHistoryStream(strings.NewReader(jsonContent)), options)
+	if err != nil || len(results) != 1 {
+		t.Fatalf("unexpected search outcome: %v, %v", results, err)
+	}
+	if err := sm.SaveCache(path, "export-v1"); err != nil {
+		t.Fatalf("SaveCache() error = %v", err)
+	}
+
+	// A new run answers from the cache without reading the export
+	sm = NewSearchManager(nil)
+	if err := sm.LoadCache(path, "export-v1"); err != nil {
+		t.Fatalf("LoadCache() error = %v", err)
+	}
+	cached, err := sm.SearchStream(context.Background(), utils.NewHistoryStream(strings.NewReader("not json")), options)
+	if err != nil {
+		t.Fatalf("expected a cache hit, got error %v", err)
+	}
+	if len(cached) != 1 || cached[0].Message.OriginalId != "m1" || cached[0].ConversationName != "First" {
+		t.Errorf("unexpected cached results: %+v", cached)
+	}
+
+	// Once the export changes the persisted results are ignored
+	sm = NewSearchManager(nil)
+	if err := sm.LoadCache(path, "export-v2"); err != nil {
+		t.Fatalf("LoadCache() error = %v", err)
+	}
+	if sm.cache.len() != 0 {
+		t.Errorf("expected a stale cache to be ignored, got %d entries", sm.cache.len())
+	}
+
+	// So are results saved by an older version
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale := cacheFile{Version: cacheFormatVersion - 1, Key: "export-v1", Entries: []cacheEntry{{Key: sm.buildCacheKey(options), Results: results}}}
+	if err := gob.NewEncoder(file).Encode(&stale); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+	if err := sm.LoadCache(path, "export-v1"); err != nil || sm.cache.len() != 0 {
+		t.Errorf("expected an outdated cache to be ignored, got %v with %d entries", err, sm.cache.len())
+	}
+
+	// Missing and corrupt files leave the cache empty
+	if err := sm.LoadCache(filepath.Join(t.TempDir(), "missing"), "export-v1"); err != nil {
+		t.Errorf("expected a missing cache to be ignored, got %v", err)
+	}
+	if err := os.WriteFile(path, []byte("garbage"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := sm.LoadCache(path, "export-v1"); err != nil || sm.cache.len() != 0 {
+		t.Errorf("expected a corrupt cache to be ignored, got %v with %d entries", err, sm.cache.len())
+	}
+}
+
+func TestSearchManager_CacheSkipsCancelledSearches(t *testing.T) {
+	history := &models.SkypeHistoryRoot{
+		Conversations: []models.SkypeConversation{
+			{MessageList: []models.SkypeMessage{{Content: "apple", MessageType: "Text"}}},
+		},
+	}
+	sm := NewSearchManager(history)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := sm.Search(ctx, SearchOptions{Query: "apple", SearchInContent: true}); err == nil {
+		t.Fatal("expected the cancelled search to fail")
+	}
+	if sm.cache.len() != 0 {
+		t.Errorf("expected partial results not to be cached, got %d entries", sm.cache.len())
+	}
+}
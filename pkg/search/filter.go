@@ -0,0 +1,49 @@
+package search
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/beckxie/skype-history-viewer-cli/pkg/models"
+)
+
+// errOwnerUnknown is returned when FromOwner is set, or the query looks for
+// mentions:me, but the export does not say who its owner is
+var errOwnerUnknown = fmt.Errorf("the export does not name its owner, can't filter by own messages or mentions")
+
+// hasMessageFilters reports whether options restrict messages by type,
+// attachments, links, sender id or owner
+func hasMessageFilters(options SearchOptions) bool {
+	return len(options.MessageTypes) > 0 || options.HasAttachments || options.HasUrlPreviews ||
+		len(options.SenderIds) > 0 || options.FromOwner != nil
+}
+
+// checkOwner makes sure the owner is known when the search refers to it
+func checkOwner(m *matcher) error {
+	usesOwner := m.options.FromOwner != nil || (m.query != nil && m.query.usesOwner)
+	if usesOwner && m.options.OwnerId == "" {
+		return errOwnerUnknown
+	}
+	return nil
+}
+
+// keepMessage applies the structured message filters of options. Types and
+// sender ids are compared exactly.
+func keepMessage(msg *models.SkypeMessage, options SearchOptions) bool {
+	if len(options.MessageTypes) > 0 && !slices.Contains(options.MessageTypes, msg.MessageType) {
+		return false
+	}
+	if options.HasAttachments && !msg.HasAttachments() {
+		return false
+	}
+	if options.HasUrlPreviews && !msg.HasUrlPreviews() {
+		return false
+	}
+	if len(options.SenderIds) > 0 && !slices.Contains(options.SenderIds, msg.From) {
+		return false
+	}
+	if options.FromOwner != nil && msg.IsSentBy(options.OwnerId) != *options.FromOwner {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,91 @@
+package search
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/beckxie/skype-history-viewer-cli/pkg/models"
+)
+
+func TestSearchManager_MessageFilters(t *testing.T) {
+	previews := `[{"url":"https://example.com"}]`
+	history := &models.SkypeHistoryRoot{
+		UserId: "live:me",
+		Conversations: []models.SkypeConversation{
+			{
+				Id:          "c1",
+				DisplayName: stringPtr("Team"),
+				MessageList: []models.SkypeMessage{
+					{OriginalId: "m1", From: "8:live:me", Content: "report draft", MessageType: "RichText"},
+					{OriginalId: "m2", From: "8:live:alice", Content: "report.pdf", MessageType: "RichText/Media_GenericFile", AmsReferences: []string{"0-abc"}},
+					{OriginalId: "m3", From: "8:live:bob", Content: "report at https://example.com", MessageType: "RichText", Properties: &models.MessageProperties{UrlPreviews: &previews}},
+					{OriginalId: "m4", From: "8:live:alice", Content: "call about the report", MessageType: "Event/Call"},
+				},
+			},
+		},
+	}
+	sm := NewSearchManager(history)
+
+	owner, others := true, false
+	tests := []struct {
+		name    string
+		options SearchOptions
+		want    []string
+	}{
+		{"no filters", SearchOptions{}, []string{"m1", "m2", "m3", "m4"}},
+		{"message types", SearchOptions{MessageTypes: []string{"RichText/Media_GenericFile", "Event/Call"}}, []string{"m2", "m4"}},
+		{"type is exact", SearchOptions{MessageTypes: []string{"RichText"}}, []string{"m1", "m3"}},
+		{"attachments", SearchOptions{HasAttachments: true}, []string{"m2"}},
+		{"url previews", SearchOptions{HasUrlPreviews: true}, []string{"m3"}},
+		{"sender ids", SearchOptions{SenderIds: []string{"8:live:alice"}}, []string{"m2", "m4"}},
+		{"sender id is exact", SearchOptions{SenderIds: []string{"alice"}}, nil},
+		{"owner", SearchOptions{FromOwner: &owner}, []string{"m1"}},
+		{"others", SearchOptions{FromOwner: &others}, []string{"m2", "m3", "m4"}},
+		{"combined", SearchOptions{FromOwner: &others, MessageTypes: []string{"Event/Call"}}, []string{"m4"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := tt.options
+			options.Query = "report"
+			options.SearchInContent = true
+			results, err := sm.Search(context.Background(), options)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var got []string
+			for _, result := range results {
+				got = append(got, result.Message.OriginalId)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSearchManager_OwnerUnknown(t *testing.T) {
+	sm := NewSearchManager(&models.SkypeHistoryRoot{})
+	owner := true
+	_, err := sm.Search(context.Background(), SearchOptions{Query: "x", SearchInContent: true, FromOwner: &owner})
+	if err != errOwnerUnknown {
+		t.Errorf("expected errOwnerUnknown, got %v", err)
+	}
+}
+
+func TestSearchManager_MentionsMeOwnerUnknown(t *testing.T) {
+	sm := NewSearchManager(&models.SkypeHistoryRoot{Conversations: []models.SkypeConversation{{
+		MessageList: []models.SkypeMessage{
+			{Content: `<at id="8:live:james">James</at> hi`, MessageType: "Text", Timestamp: "2024-01-01T10:00:00Z"},
+		},
+	}}})
+
+	_, err := sm.Search(context.Background(), SearchOptions{Query: "mentions:me", AdvancedQuery: true})
+	if err != errOwnerUnknown {
+		t.Errorf("expected errOwnerUnknown, got %v", err)
+	}
+	if err := ValidateOptions(SearchOptions{Query: "mentions:me", AdvancedQuery: true}); err != nil {
+		t.Errorf("expected the query to validate before the owner is known, got %v", err)
+	}
+}
@@ -0,0 +1,197 @@
+package search
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/beckxie/skype-history-viewer-cli/pkg/analyzer"
+	"github.com/beckxie/skype-history-viewer-cli/pkg/models"
+	"github.com/beckxie/skype-history-viewer-cli/pkg/viewer"
+)
+
+// fuzzyTerm is a query term and how far a word may be from it
+type fuzzyTerm struct {
+	runes       []rune
+	maxDistance int
+}
+
+// fuzzyMatcher matches every query term against the closest word of a text
+type fuzzyMatcher struct {
+	terms []fuzzyTerm
+}
+
+// fuzzyMatch is the outcome of matching a text
+type fuzzyMatch struct {
+	spans    []span
+	variants []string
+	distance int
+}
+
+// newFuzzyMatcher splits the query into terms. A maxDistance of 0 picks
+// one based on each term's length.
+func newFuzzyMatcher(query string, maxDistance int) *fuzzyMatcher {
+	fm := &fuzzyMatcher{}
+	for _, term := range analyzer.Terms(query) {
+		runes := []rune(term)
+		distance := maxDistance
+		if distance <= 0 {
+			distance = autoDistance(len(runes))
+		}
+		fm.terms = append(fm.terms, fuzzyTerm{runes: runes, maxDistance: distance})
+	}
+	return fm
+}
+
+// autoDistance allows more typos in longer words: none up to 2 characters,
+// one up to 5 and two beyond
+func autoDistance(length int) int {
+	switch {
+	case length <= 2:
+		return 0
+	case length <= 5:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// match finds, for every term, the closest word of text. It fails unless
+// each term has a word within its distance.
+func (fm *fuzzyMatcher) match(text string) (fuzzyMatch, bool) {
+	if len(fm.terms) == 0 {
+		return fuzzyMatch{}, false
+	}
+
+	tokens := analyzer.Tokenize(text)
+	words := make([][]rune, len(tokens))
+	for i, token := range tokens {
+		words[i] = []rune(token.Term)
+	}
+
+	var result fuzzyMatch
+	for _, term := range fm.terms {
+		best, bestDistance := -1, term.maxDistance+1
+		for i, word := range words {
+			if d := editDistance(term.runes, word, bestDistance-1); d < bestDistance {
+				best, bestDistance = i, d
+				if d == 0 {
+					break
+				}
+			}
+		}
+		if best == -1 {
+			return fuzzyMatch{}, false
+		}
+
+		token := tokens[best]
+		result.spans = append(result.spans, span{token.Start, token.End})
+		result.variants = append(result.variants, text[token.Start:token.End])
+		result.distance += bestDistance
+	}
+
+	// Also highlight repeated occurrences of the matched words
+	matched := make(map[string]bool)
+	for _, s := range result.spans {
+		matched[text[s.start:s.end]] = true
+	}
+	for _, token := range tokens {
+		if matched[text[token.Start:token.End]] {
+			result.spans = append(result.spans, span{token.Start, token.End})
+		}
+	}
+
+	return result, true
+}
+
+// editDistance returns the Damerau-Levenshtein distance (optimal string
+// alignment) between a and b, or limit+1 as soon as it is known to exceed
+// limit
+func editDistance(a, b []rune, limit int) int {
+	if diff := len(a) - len(b); diff > limit || -diff > limit {
+		return limit + 1
+	}
+
+	// Three rolling rows: two back for transpositions, previous and current
+	prevPrev := make([]int, len(b)+1)
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		rowMin := curr[0]
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				curr[j] = min(curr[j], prevPrev[j-2]+1)
+			}
+			rowMin = min(rowMin, curr[j])
+		}
+		if rowMin > limit {
+			return limit + 1
+		}
+		prevPrev, prev, curr = prev, curr, prevPrev
+	}
+
+	return min(prev[len(b)], limit+1)
+}
+
+// checkFuzzy matches a message's content and sender within the configured
+// edit distance
+func (sm *SearchManager) checkFuzzy(msg *models.SkypeMessage, m *matcher) *viewer.SearchResult {
+	var best *fuzzyMatch
+	contentMatch := false
+	senderMatch := false
+	matchContext := ""
+
+	if m.options.SearchInContent {
+		content := msg.GetDisplayText()
+		if match, ok := m.fuzzy.match(content); ok {
+			contentMatch = true
+			best = &match
+			matchContext = sm.extractContext(content, match.spans, m.options.ContextWidth)
+		}
+	}
+
+	if m.options.SearchInSender {
+		if match, ok := m.fuzzy.match(msg.GetSenderDisplayName()); ok {
+			senderMatch = true
+			if best == nil || match.distance < best.distance {
+				best = &match
+			}
+		}
+	}
+
+	if best == nil {
+		return nil
+	}
+
+	matchType := "content"
+	if contentMatch && senderMatch {
+		matchType = "both"
+	} else if senderMatch {
+		matchType = "sender"
+	}
+
+	return &viewer.SearchResult{
+		Message:        *msg,
+		MatchContext:   matchContext,
+		MatchType:      matchType,
+		MatchedVariant: strings.Join(best.variants, " "),
+		Distance:       best.distance,
+	}
+}
+
+// sortByDistance orders fuzzy results from the closest match, keeping the
+// existing order between equally close ones
+func sortByDistance(results []viewer.SearchResult) {
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Distance < results[j].Distance
+	})
+}
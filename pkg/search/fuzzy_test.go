@@ -0,0 +1,143 @@
+package search
+
+import (
+	"context"
+	"testing"
+
+	"github.com/beckxie/skype-history-viewer-cli/pkg/models"
+)
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b  string
+		limit int
+		want  int
+	}{
+		{"meeting", "meeting", 2, 0},
+		{"meeting", "meeitng", 2, 1}, // transposition
+		{"meeting", "meting", 2, 1},  // deletion
+		{"meeting", "meetings", 2, 1},
+		{"receive", "recieve", 2, 1},
+		{"kitten", "sitting", 3, 3},
+		{"kitten", "sitting", 2, 3}, // capped at limit+1
+		{"ca", "abc", 3, 3},         // optimal string alignment, not full Damerau
+		{"會議", "會義", 1, 1},
+		{"a", "abcdef", 2, 3},
+	}
+
+	for _, tt := range tests {
+		if got := editDistance([]rune(tt.a), []rune(tt.b), tt.limit); got != tt.want {
+			t.Errorf("editDistance(%q, %q, %d) = %d, want %d", tt.a, tt.b, tt.limit, got, tt.want)
+		}
+	}
+}
+
+func TestSearchManager_FuzzySearch(t *testing.T) {
+	history := &models.SkypeHistoryRoot{
+		Conversations: []models.SkypeConversation{
+			{
+				MessageList: []models.SkypeMessage{
+					{Content: "Did you recieve the invoice?", From: "Jonathan", MessageType: "Text", Timestamp: "2024-01-01T10:00:00Z"},
+					{Content: "I received it", From: "Jon", MessageType: "Text", Timestamp: "2024-01-01T10:01:00Z"},
+					{Content: "unrelated", From: "Mary", MessageType: "Text", Timestamp: "2024-01-01T10:02:00Z"},
+					{Content: "receive receive", From: "Mary", MessageType: "Text", Timestamp: "2024-01-01T10:03:00Z"},
+				},
+			},
+		},
+	}
+	sm := NewSearchManager(history)
+
+	t.Run("limit keeps the closest matches", func(t *testing.T) {
+		// The exact match sits after two weaker ones in the export
+		results, err := sm.Search(context.Background(), SearchOptions{
+			Query:           "receive",
+			Fuzzy:           true,
+			SearchInContent: true,
+			Limit:           1,
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(results) != 1 || results[0].Message.Content != "receive receive" {
+			t.Errorf("expected the exact match, got %+v", results)
+		}
+	})
+
+	t.Run("ranked by distance", func(t *testing.T) {
+		results, err := sm.Search(context.Background(), SearchOptions{
+			Query:           "receive",
+			Fuzzy:           true,
+			SearchInContent: true,
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(results) != 3 {
+			t.Fatalf("expected 3 results, got %d", len(results))
+		}
+
+		want := []struct {
+			variant  string
+			distance int
+		}{{"receive", 0}, {"recieve", 1}, {"received", 1}}
+		for i, w := range want {
+			if results[i].MatchedVariant != w.variant || results[i].Distance != w.distance {
+				t.Errorf("result %d: got %q (%d), want %q (%d)",
+					i, results[i].MatchedVariant, results[i].Distance, w.variant, w.distance)
+			}
+		}
+	})
+
+	t.Run("max distance", func(t *testing.T) {
+		results, _ := sm.Search(context.Background(), SearchOptions{
+			Query:           "receive",
+			Fuzzy:           true,
+			MaxDistance:     2,
+			SearchInContent: true,
+		})
+		if len(results) != 3 {
+			t.Errorf("expected 3 results, got %d", len(results))
+		}
+
+		results, _ = sm.Search(context.Background(), SearchOptions{
+			Query:           "recieved",
+			Fuzzy:           true,
+			MaxDistance:     1,
+			SearchInContent: true,
+		})
+		if len(results) != 2 {
+			t.Errorf("expected recieve and received within one edit, got %d", len(results))
+		}
+	})
+
+	t.Run("every word must match", func(t *testing.T) {
+		results, _ := sm.Search(context.Background(), SearchOptions{
+			Query:           "recieve invoise",
+			Fuzzy:           true,
+			SearchInContent: true,
+		})
+		if len(results) != 1 || results[0].MatchedVariant != "recieve invoice" || results[0].Distance != 1 {
+			t.Errorf("unexpected results: %+v", results)
+		}
+	})
+
+	t.Run("sender", func(t *testing.T) {
+		results, _ := sm.Search(context.Background(), SearchOptions{
+			Query:          "jonathon",
+			Fuzzy:          true,
+			SearchInSender: true,
+		})
+		if len(results) != 1 || results[0].MatchType != "sender" || results[0].MatchedVariant != "Jonathan" {
+			t.Errorf("unexpected results: %+v", results)
+		}
+	})
+
+	t.Run("incompatible options", func(t *testing.T) {
+		if _, err := sm.Search(context.Background(), SearchOptions{Query: "x", Fuzzy: true, RegexSearch: true}); err == nil {
+			t.Error("expected error combining fuzzy and regex")
+		}
+		if _, err := sm.Search(context.Background(), SearchOptions{Query: "!!", Fuzzy: true}); err == nil {
+			t.Error("expected error for query without words")
+		}
+	})
+}
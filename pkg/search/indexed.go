@@ -0,0 +1,243 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+
+	"github.com/beckxie/skype-history-viewer-cli/pkg/analyzer"
+	"github.com/beckxie/skype-history-viewer-cli/pkg/fulltext"
+	"github.com/beckxie/skype-history-viewer-cli/pkg/index"
+	"github.com/beckxie/skype-history-viewer-cli/pkg/models"
+	"github.com/beckxie/skype-history-viewer-cli/pkg/progress"
+	"github.com/beckxie/skype-history-viewer-cli/pkg/utils"
+	"github.com/beckxie/skype-history-viewer-cli/pkg/viewer"
+)
+
+// Result orderings
+const (
+	SortRelevance = "relevance" // BM25 score, only available through the full-text index; scans keep export order
+	SortDate      = "date"      // message time, oldest first
+)
+
+// IndexableQuery reports whether options can be answered from the
+// full-text index. Regular expressions, advanced and fuzzy queries need a
+// scan.
+func IndexableQuery(options SearchOptions) bool {
+	if options.RegexSearch || options.AdvancedQuery || options.Fuzzy {
+		return false
+	}
+	if !options.SearchInContent && !options.SearchInSender {
+		return false
+	}
+	return len(analyzer.Terms(options.Query)) > 0
+}
+
+// SearchIndex answers a plain query from the full-text segment of an
+// indexed export, reading only candidate messages from the export.
+// Candidates are checked with the same rules as Search, so both return the
+// same matches, only ranked by relevance unless options ask for date order.
+func (sm *SearchManager) SearchIndex(ctx context.Context, idx *index.Index, segment *fulltext.Segment, options SearchOptions) ([]viewer.SearchResult, error) {
+	if options.OwnerId == "" {
+		options.OwnerId = idx.UserId
+	}
+
+	// Ranked results are cached apart from scanned ones, which keep export order
+	cacheKey := "index|" + sm.buildCacheKey(options)
+	if cached, ok := sm.cache.get(cacheKey); ok {
+		return cached, nil
+	}
+
+	m, err := newMatcher(options)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkOwner(m); err != nil {
+		return nil, err
+	}
+
+	hits, err := segment.Search(fulltext.Query{
+		Terms:   analyzer.Terms(options.Query),
+		Content: options.SearchInContent,
+		Sender:  options.SearchInSender,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Apply the filters answerable from metadata before touching the export
+	candidates := hits[:0]
+	for _, hit := range hits {
+		if hit.Doc.Conversation >= len(idx.Conversations) {
+			return nil, fmt.Errorf("text index does not match the export index")
+		}
+		entry := &idx.Conversations[hit.Doc.Conversation]
+		if hit.Doc.Message >= len(entry.Messages) {
+			return nil, fmt.Errorf("text index does not match the export index")
+		}
+
+		if options.ConversationFilter != "" && !analyzer.Contains(entry.DisplayName, options.ConversationFilter) {
+			continue
+		}
+		if options.DateFrom != nil || options.DateTo != nil {
+			t, ok := entry.Messages[hit.Doc.Message].Time()
+			if !ok {
+				continue
+			}
+			if options.DateFrom != nil && t.Before(*options.DateFrom) {
+				continue
+			}
+			if options.DateTo != nil && t.After(*options.DateTo) {
+				continue
+			}
+		}
+		candidates = append(candidates, hit)
+	}
+
+	if options.Sort == SortDate {
+		sort.SliceStable(candidates, func(i, j int) bool {
+			ti := messageEntry(idx, candidates[i].Doc).Timestamp
+			tj := messageEntry(idx, candidates[j].Doc).Timestamp
+			if ti == 0 {
+				// Messages with unreadable timestamps go last
+				return false
+			}
+			return tj == 0 || ti < tj
+		})
+	}
+
+	file, err := utils.OpenExportFile(idx.SourceFile, idx.DataOffset)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	results := []viewer.SearchResult{}
+
+	reporter := progress.Or(options.Reporter)
+	defer reporter.SearchFinished()
+
+	for i, hit := range candidates {
+		select {
+		case <-ctx.Done():
+			return results, ctx.Err()
+		default:
+		}
+
+		reporter.SearchProgress(int64(i+1), int64(len(candidates)), "candidates")
+
+		msg, err := file.ReadMessage(messageEntry(idx, hit.Doc).Offset)
+		if err != nil {
+			return results, err
+		}
+
+		if hasMessageFilters(options) && !keepMessage(msg, options) {
+			continue
+		}
+
+		matchResult := sm.checkMatch(msg, m)
+		if matchResult == nil {
+			continue
+		}
+		matchResult.ConversationId = idx.Conversations[hit.Doc.Conversation].Id
+		matchResult.ConversationName = idx.Conversations[hit.Doc.Conversation].DisplayName
+		matchResult.Score = hit.Score
+		if options.ContextBefore > 0 || options.ContextAfter > 0 {
+			matchResult.Before, matchResult.After, err = surroundingMessages(file, idx, hit.Doc, options.ContextBefore, options.ContextAfter)
+			if err != nil {
+				return results, err
+			}
+		}
+		results = append(results, *matchResult)
+
+		if options.Limit > 0 && len(results) >= options.Limit {
+			break
+		}
+	}
+
+	sm.cache.put(cacheKey, results)
+	return results, nil
+}
+
+// messageEntry returns the indexed message a document refers to
+func messageEntry(idx *index.Index, ref fulltext.DocRef) index.MessageEntry {
+	return idx.Conversations[ref.Conversation].Messages[ref.Message]
+}
+
+// surroundingMessages reads up to before non-system messages preceding the
+// referenced one and up to after following it, mirroring
+// models.SkypeConversation.SurroundingMessages
+func surroundingMessages(file *utils.ExportFile, idx *index.Index, ref fulltext.DocRef, before, after int) (preceding, following []models.SkypeMessage, err error) {
+	messages := idx.Conversations[ref.Conversation].Messages
+	read := func(i int) (models.SkypeMessage, error) {
+		msg, err := file.ReadMessage(messages[i].Offset)
+		if err != nil {
+			return models.SkypeMessage{}, err
+		}
+		return *msg, nil
+	}
+
+	for i := ref.Message - 1; i >= 0 && len(preceding) < before; i-- {
+		if messages[i].System {
+			continue
+		}
+		msg, err := read(i)
+		if err != nil {
+			return nil, nil, err
+		}
+		preceding = append(preceding, msg)
+	}
+	slices.Reverse(preceding)
+
+	for i := ref.Message + 1; i < len(messages) && len(following) < after; i++ {
+		if messages[i].System {
+			continue
+		}
+		msg, err := read(i)
+		if err != nil {
+			return nil, nil, err
+		}
+		following = append(following, msg)
+	}
+
+	return preceding, following, nil
+}
+
+// sortResults applies the requested order to scanned results. Fuzzy
+// results are ordered by distance unless date order was asked for.
+func sortResults(results []viewer.SearchResult, options SearchOptions) {
+	switch {
+	case options.Sort == SortDate:
+		sortByDate(results)
+	case options.Fuzzy:
+		sortByDistance(results)
+	}
+}
+
+// sortedOrder reports whether scanned results are sorted once the scan is
+// over, which then has to find every match before the limit applies
+func sortedOrder(options SearchOptions) bool {
+	return options.Sort == SortDate || options.Fuzzy
+}
+
+// limitResults cuts results to the requested limit
+func limitResults(results []viewer.SearchResult, options SearchOptions) []viewer.SearchResult {
+	if options.Limit > 0 && len(results) > options.Limit {
+		return results[:options.Limit]
+	}
+	return results
+}
+
+// sortByDate orders results by message time, oldest first. Messages with
+// unreadable timestamps go last.
+func sortByDate(results []viewer.SearchResult) {
+	sort.SliceStable(results, func(i, j int) bool {
+		ti, errI := results[i].Message.GetTimestamp()
+		tj, errJ := results[j].Message.GetTimestamp()
+		if errI != nil {
+			return false
+		}
+		return errJ != nil || ti.Before(tj)
+	})
+}
@@ -0,0 +1,123 @@
+package search
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/beckxie/skype-history-viewer-cli/pkg/index"
+	"github.com/beckxie/skype-history-viewer-cli/pkg/utils"
+)
+
+func TestSearchManager_SearchIndex(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	exportPath := filepath.Join(t.TempDir(), "messages.json")
+	jsonContent := `{"userId": "u", "conversations": [
+		{"id": "c1", "displayName": "Team", "MessageList": [
+			{"id": "m1", "from": "alice", "content": "release is out", "messagetype": "Text", "originalarrivaltime": "2024-01-03T10:00:00Z"},
+			{"id": "m2", "from": "bob", "content": "release release release", "messagetype": "Text", "originalarrivaltime": "2024-01-02T10:00:00Z"},
+			{"id": "m3", "from": "bob", "content": "pre-release notes", "messagetype": "Text", "originalarrivaltime": "2024-01-01T10:00:00Z"}
+		]},
+		{"id": "c2", "displayName": "Family", "MessageList": [
+			{"id": "m4", "from": "carol", "content": "Released the hounds", "messagetype": "Text", "originalarrivaltime": "2024-01-04T10:00:00Z"},
+			{"id": "m5", "from": "carol", "content": "no match here", "messagetype": "Text", "originalarrivaltime": "2024-01-05T10:00:00Z"},
+			{"id": "m6", "from": "dave", "content": "明天在會議室開ＭＴＧ", "messagetype": "Text", "originalarrivaltime": "2024-01-06T10:00:00Z"}
+		]}
+	]}`
+	if err := os.WriteFile(exportPath, []byte(jsonContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	idx, err := index.Build(exportPath, utils.LoadOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := idx.Save(exportPath); err != nil {
+		t.Fatal(err)
+	}
+	segment, err := idx.OpenText(exportPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer segment.Close()
+
+	sm := NewSearchManager(nil)
+	ids := func(options SearchOptions) []string {
+		t.Helper()
+		results, err := sm.SearchIndex(context.Background(), idx, segment, options)
+		if err != nil {
+			t.Fatalf("SearchIndex error = %v", err)
+		}
+		var got []string
+		for _, result := range results {
+			got = append(got, result.Message.OriginalId)
+		}
+		return got
+	}
+
+	tests := []struct {
+		name    string
+		options SearchOptions
+		want    []string
+	}{
+		{"relevance", SearchOptions{Query: "release", SearchInContent: true, Sort: SortRelevance}, []string{"m2", "m1", "m3", "m4"}},
+		{"date", SearchOptions{Query: "release", SearchInContent: true, Sort: SortDate}, []string{"m3", "m2", "m1", "m4"}},
+		{"substring across words is verified", SearchOptions{Query: "is out", SearchInContent: true}, []string{"m1"}},
+		{"case-sensitive is verified", SearchOptions{Query: "Release", SearchInContent: true, CaseSensitive: true}, []string{"m4"}},
+		{"conversation filter", SearchOptions{Query: "release", SearchInContent: true, ConversationFilter: "family"}, []string{"m4"}},
+		{"sender", SearchOptions{Query: "carol", SearchInSender: true, Sort: SortDate}, []string{"m4", "m5"}},
+		{"limit", SearchOptions{Query: "release", SearchInContent: true, Limit: 1}, []string{"m2"}},
+		{"sender id filter", SearchOptions{Query: "release", SearchInContent: true, SenderIds: []string{"bob"}, Sort: SortDate}, []string{"m3", "m2"}},
+		{"cjk bigram", SearchOptions{Query: "會議", SearchInContent: true}, []string{"m6"}},
+		{"cjk single character", SearchOptions{Query: "室", SearchInContent: true}, []string{"m6"}},
+		{"full-width", SearchOptions{Query: "mtg", SearchInContent: true}, []string{"m6"}},
+	}
+
+	t.Run("surrounding messages", func(t *testing.T) {
+		results, err := sm.SearchIndex(context.Background(), idx, segment, SearchOptions{Query: "is out", SearchInContent: true, ContextAfter: 2})
+		if err != nil {
+			t.Fatalf("SearchIndex error = %v", err)
+		}
+		if len(results) != 1 || len(results[0].Before) != 0 || len(results[0].After) != 2 {
+			t.Fatalf("unexpected surrounding messages: %+v", results)
+		}
+		if results[0].After[0].OriginalId != "m2" || results[0].After[1].OriginalId != "m3" {
+			t.Errorf("expected m2 and m3 after the hit, got %q and %q", results[0].After[0].OriginalId, results[0].After[1].OriginalId)
+		}
+	})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ids(tt.options)
+			if len(got) != len(tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("expected %v, got %v", tt.want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestIndexableQuery(t *testing.T) {
+	tests := []struct {
+		options SearchOptions
+		want    bool
+	}{
+		{SearchOptions{Query: "hello", SearchInContent: true}, true},
+		{SearchOptions{Query: "!!", SearchInContent: true}, false},
+		{SearchOptions{Query: "hello", SearchInContent: true, RegexSearch: true}, false},
+		{SearchOptions{Query: "hello", SearchInContent: true, AdvancedQuery: true}, false},
+		{SearchOptions{Query: "hello"}, false},
+	}
+
+	for _, tt := range tests {
+		if got := IndexableQuery(tt.options); got != tt.want {
+			t.Errorf("IndexableQuery(%+v) = %v, want %v", tt.options, got, tt.want)
+		}
+	}
+}
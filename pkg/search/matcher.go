@@ -0,0 +1,124 @@
+package search
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/beckxie/skype-history-viewer-cli/pkg/analyzer"
+)
+
+// matcher is a search query prepared once per search
+type matcher struct {
+	options SearchOptions
+	pattern *regexp.Regexp // compiled query in regex mode
+	needle  string         // normalized query, lowercased unless case-sensitive
+	query   *compiledQuery // parsed query in advanced mode
+	fuzzy   *fuzzyMatcher  // query terms in fuzzy mode
+}
+
+// newMatcher compiles the query of the given options
+func newMatcher(options SearchOptions) (*matcher, error) {
+	m := &matcher{options: options}
+
+	if options.Fuzzy {
+		if options.RegexSearch || options.AdvancedQuery {
+			return nil, fmt.Errorf("fuzzy search can't be combined with regular expressions or advanced queries")
+		}
+		m.fuzzy = newFuzzyMatcher(options.Query, options.MaxDistance)
+		if len(m.fuzzy.terms) == 0 {
+			return nil, fmt.Errorf("fuzzy search needs at least one word in the query")
+		}
+		return m, nil
+	}
+
+	if options.AdvancedQuery {
+		query, err := parseQuery(options)
+		if err != nil {
+			return nil, err
+		}
+		m.query = query
+		return m, nil
+	}
+
+	if options.RegexSearch {
+		expr := options.Query
+		if !options.CaseSensitive {
+			expr = "(?i)" + expr
+		}
+		pattern, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression: %w", err)
+		}
+		m.pattern = pattern
+		return m, nil
+	}
+
+	m.needle = analyzer.Fold(options.Query, !options.CaseSensitive)
+	return m, nil
+}
+
+// ValidateOptions reports whether the query of options compiles, so that
+// syntax errors can be shown before any export is read
+func ValidateOptions(options SearchOptions) error {
+	_, err := newMatcher(options)
+	return err
+}
+
+// find returns the byte span of the first non-empty match in text, like
+// findAll. Matching runs on the NFKC-normalized text, so full-width and
+// other compatibility variants match their usual form.
+func (m *matcher) find(text string) (start, end int, ok bool) {
+	if m.pattern != nil {
+		normalized := analyzer.Normalize(text, false)
+		for _, loc := range m.pattern.FindAllStringIndex(normalized.Text, -1) {
+			if loc[0] == loc[1] {
+				continue
+			}
+			start, end := normalized.Span(loc[0], loc[1])
+			return start, end, true
+		}
+		return 0, 0, false
+	}
+
+	normalized := analyzer.Normalize(text, !m.options.CaseSensitive)
+	index := strings.Index(normalized.Text, m.needle)
+	if index == -1 {
+		return 0, 0, false
+	}
+	start, end = normalized.Span(index, index+len(m.needle))
+	return start, end, true
+}
+
+// findAll returns the byte spans of every non-overlapping, non-empty match
+// in text
+func (m *matcher) findAll(text string) []span {
+	var spans []span
+
+	if m.pattern != nil {
+		normalized := analyzer.Normalize(text, false)
+		for _, loc := range m.pattern.FindAllStringIndex(normalized.Text, -1) {
+			if loc[0] == loc[1] {
+				continue
+			}
+			start, end := normalized.Span(loc[0], loc[1])
+			spans = append(spans, span{start, end})
+		}
+		return spans
+	}
+
+	if m.needle == "" {
+		return nil
+	}
+	normalized := analyzer.Normalize(text, !m.options.CaseSensitive)
+	for offset := 0; ; {
+		index := strings.Index(normalized.Text[offset:], m.needle)
+		if index == -1 {
+			break
+		}
+		start, end := normalized.Span(offset+index, offset+index+len(m.needle))
+		spans = append(spans, span{start, end})
+		offset += index + len(m.needle)
+	}
+	return spans
+}
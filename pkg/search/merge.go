@@ -0,0 +1,59 @@
+package search
+
+import (
+	"sort"
+
+	"github.com/beckxie/skype-history-viewer-cli/pkg/viewer"
+)
+
+// ExportResults are the results of searching one of several exports
+type ExportResults struct {
+	Label   string // how the export is named in results
+	Results []viewer.SearchResult
+}
+
+// MergeResults combines the results of searching several exports with the
+// same options. Every result is labeled with the exports holding its
+// message; a message found in more than one export is kept once, as found
+// in the first. The merged results are ordered and limited like those of a
+// single search.
+func MergeResults(sets []ExportResults, options SearchOptions) []viewer.SearchResult {
+	merged := []viewer.SearchResult{}
+	seen := make(map[string]int)
+	for _, set := range sets {
+		for _, result := range set.Results {
+			key := messageKey(&result)
+			if i, ok := seen[key]; ok {
+				merged[i].Exports = append(merged[i].Exports, set.Label)
+				continue
+			}
+			seen[key] = len(merged)
+			result.Exports = []string{set.Label}
+			merged = append(merged, result)
+		}
+	}
+
+	sortResults(merged, options)
+	if options.Sort == SortRelevance {
+		// Results ranked by the full-text index come first, best first
+		sort.SliceStable(merged, func(i, j int) bool {
+			return merged[i].Score > merged[j].Score
+		})
+	}
+
+	if options.Limit > 0 && len(merged) > options.Limit {
+		merged = merged[:options.Limit]
+	}
+	return merged
+}
+
+// messageKey identifies a message across exports. Messages keep their id
+// and conversation in every export they appear in; those without an id
+// fall back to their sender, time and content.
+func messageKey(result *viewer.SearchResult) string {
+	msg := &result.Message
+	if msg.OriginalId != "" {
+		return result.ConversationId + "\x00" + msg.OriginalId
+	}
+	return result.ConversationId + "\x00" + msg.From + "\x00" + msg.Timestamp + "\x00" + msg.Content
+}
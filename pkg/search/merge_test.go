@@ -0,0 +1,94 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/beckxie/skype-history-viewer-cli/pkg/models"
+	"github.com/beckxie/skype-history-viewer-cli/pkg/viewer"
+)
+
+func mergeResult(conversationId, id, timestamp string, score float64) viewer.SearchResult {
+	return viewer.SearchResult{
+		ConversationId: conversationId,
+		Message:        models.SkypeMessage{OriginalId: id, Timestamp: timestamp},
+		Score:          score,
+	}
+}
+
+func TestMergeResults(t *testing.T) {
+	alice := ExportResults{Label: "alice", Results: []viewer.SearchResult{
+		mergeResult("c1", "m1", "2024-01-03T00:00:00Z", 0),
+		mergeResult("c1", "m2", "2024-01-01T00:00:00Z", 0),
+	}}
+	bob := ExportResults{Label: "bob", Results: []viewer.SearchResult{
+		mergeResult("c1", "m2", "2024-01-01T00:00:00Z", 0),
+		mergeResult("c2", "m2", "2024-01-02T00:00:00Z", 0),
+	}}
+
+	tests := []struct {
+		name    string
+		options SearchOptions
+		want    []string
+	}{
+		{"export order", SearchOptions{}, []string{"c1/m1", "c1/m2", "c2/m2"}},
+		{"date order", SearchOptions{Sort: SortDate}, []string{"c1/m2", "c2/m2", "c1/m1"}},
+		{"limit", SearchOptions{Sort: SortDate, Limit: 2}, []string{"c1/m2", "c2/m2"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged := MergeResults([]ExportResults{alice, bob}, tt.options)
+
+			var got []string
+			for _, result := range merged {
+				got = append(got, result.ConversationId+"/"+result.Message.OriginalId)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	// A message in both exports is kept once and labeled with both
+	merged := MergeResults([]ExportResults{alice, bob}, SearchOptions{})
+	wantExports := [][]string{{"alice"}, {"alice", "bob"}, {"bob"}}
+	for i, result := range merged {
+		if !reflect.DeepEqual(result.Exports, wantExports[i]) {
+			t.Errorf("result %d: expected exports %v, got %v", i, wantExports[i], result.Exports)
+		}
+	}
+
+	// The inputs are left untouched
+	if alice.Results[1].Exports != nil {
+		t.Error("expected the input results not to be labeled")
+	}
+}
+
+func TestMergeResultsByRelevance(t *testing.T) {
+	indexed := ExportResults{Label: "a", Results: []viewer.SearchResult{
+		mergeResult("c1", "m1", "", 1.5),
+		mergeResult("c1", "m2", "", 0.5),
+	}}
+	scanned := ExportResults{Label: "b", Results: []viewer.SearchResult{
+		mergeResult("c2", "m3", "", 0),
+		mergeResult("c2", "m4", "", 2.5),
+	}}
+
+	var got []string
+	for _, result := range MergeResults([]ExportResults{scanned, indexed}, SearchOptions{Sort: SortRelevance}) {
+		got = append(got, result.Message.OriginalId)
+	}
+	if want := []string{"m4", "m1", "m2", "m3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestMessageKeyWithoutId(t *testing.T) {
+	a := viewer.SearchResult{ConversationId: "c", Message: models.SkypeMessage{From: "x", Timestamp: "t", Content: "hi"}}
+	b := a
+	b.Message.Content = "bye"
+	if messageKey(&a) == messageKey(&b) {
+		t.Error("expected messages without ids to be told apart by content")
+	}
+}
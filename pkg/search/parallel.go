@@ -0,0 +1,160 @@
+package search
+
+import (
+	"context"
+	"io"
+	"runtime"
+	"sync"
+
+	"github.com/beckxie/skype-history-viewer-cli/pkg/models"
+	"github.com/beckxie/skype-history-viewer-cli/pkg/viewer"
+)
+
+// searchChunkSize caps how many messages one job covers, so that a single
+// huge conversation is still spread across workers
+const searchChunkSize = 2048
+
+// searchJob is a run of messages of one conversation
+type searchJob struct {
+	seq        int
+	conv       *models.SkypeConversation
+	start, end int
+}
+
+// splitConversation cuts a conversation into jobs of at most
+// searchChunkSize messages. Empty conversations still yield one job.
+// Conversations stay whole when surrounding messages are requested, since
+// copying them must not race with another worker reading its own chunk.
+func splitConversation(conv *models.SkypeConversation, options SearchOptions) []searchJob {
+	chunkSize := searchChunkSize
+	if options.ContextBefore > 0 || options.ContextAfter > 0 {
+		chunkSize = max(len(conv.MessageList), 1)
+	}
+
+	var jobs []searchJob
+	for start := 0; start == 0 || start < len(conv.MessageList); start += chunkSize {
+		end := min(start+chunkSize, len(conv.MessageList))
+		jobs = append(jobs, searchJob{conv: conv, start: start, end: end})
+	}
+	return jobs
+}
+
+// searchParallel searches the jobs returned by next on a pool of workers.
+// next is called from a single goroutine and returns io.EOF when there is
+// no more work. Results are handed to emit in job order, one job's worth at
+// a time and never concurrently, so the outcome is the same as searching
+// serially: once Limit results have been emitted, or emit returns false,
+// the remaining work is cancelled. Sorted orders ignore Limit here, and
+// onMessage may be called concurrently.
+func (sm *SearchManager) searchParallel(ctx context.Context, m *matcher, next func() (searchJob, error), onMessage func(), emit func(results []viewer.SearchResult) bool) error {
+	workCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	workers := m.options.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	limit := m.options.Limit
+	if sortedOrder(m.options) {
+		// Sorted results are cut to the limit once every match is known
+		limit = 0
+	}
+
+	var (
+		mu       sync.Mutex
+		finished = make(map[int][]viewer.SearchResult)
+		nextSeq  int
+		emitted  int
+		stopped  bool // limit reached or emit declined more results
+	)
+	complete := func(seq int, results []viewer.SearchResult) {
+		mu.Lock()
+		defer mu.Unlock()
+		if stopped {
+			return
+		}
+
+		finished[seq] = results
+		for {
+			results, ok := finished[nextSeq]
+			if !ok {
+				return
+			}
+			delete(finished, nextSeq)
+			nextSeq++
+
+			if limit > 0 && emitted+len(results) >= limit {
+				results = results[:limit-emitted]
+				stopped = true
+			}
+			emitted += len(results)
+			if len(results) > 0 && !emit(results) {
+				stopped = true
+			}
+			if stopped {
+				cancel()
+				return
+			}
+		}
+	}
+
+	// A single feeder keeps next free of concurrent calls. A failing next
+	// stops the feed but lets the jobs already handed out finish.
+	jobs := make(chan searchJob)
+	var feedErr error
+	go func() {
+		defer close(jobs)
+		for seq := 0; ; seq++ {
+			job, err := next()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				feedErr = err
+				return
+			}
+
+			job.seq = seq
+			select {
+			case jobs <- job:
+			case <-workCtx.Done():
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				var results []viewer.SearchResult
+				// Only cancellation fails a job, and its results are then dropped
+				if _, err := sm.searchConversation(workCtx, job.conv, job.start, job.end, m, &results, onMessage); err != nil {
+					continue
+				}
+				complete(job.seq, results)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if stopped {
+		return nil
+	}
+	return feedErr
+}
+
+// collectParallel runs searchParallel and gathers every result
+func (sm *SearchManager) collectParallel(ctx context.Context, m *matcher, next func() (searchJob, error), onMessage func()) ([]viewer.SearchResult, error) {
+	results := []viewer.SearchResult{}
+	err := sm.searchParallel(ctx, m, next, onMessage, func(batch []viewer.SearchResult) bool {
+		results = append(results, batch...)
+		return true
+	})
+	return results, err
+}
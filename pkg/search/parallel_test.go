@@ -0,0 +1,105 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/beckxie/skype-history-viewer-cli/pkg/models"
+	"github.com/beckxie/skype-history-viewer-cli/pkg/viewer"
+)
+
+// parallelHistory has many small conversations and one spanning several
+// chunks
+func parallelHistory() *models.SkypeHistoryRoot {
+	history := &models.SkypeHistoryRoot{}
+	for c := 0; c < 20; c++ {
+		conv := models.SkypeConversation{Id: fmt.Sprintf("c%d", c)}
+		size := 50
+		if c == 7 {
+			size = 3*searchChunkSize + 10
+		}
+		for i := 0; i < size; i++ {
+			content := "filler"
+			if i%7 == 0 {
+				content = fmt.Sprintf("needle %d", i)
+			}
+			conv.MessageList = append(conv.MessageList, models.SkypeMessage{
+				OriginalId:  fmt.Sprintf("c%d-m%d", c, i),
+				Content:     content,
+				MessageType: "Text",
+				Timestamp:   "2024-01-01T10:00:00Z",
+			})
+		}
+		history.Conversations = append(history.Conversations, conv)
+	}
+	return history
+}
+
+func resultIds(results []viewer.SearchResult) []string {
+	ids := make([]string, len(results))
+	for i, result := range results {
+		ids[i] = result.Message.OriginalId
+	}
+	return ids
+}
+
+func TestSearchManager_ParallelMatchesSerial(t *testing.T) {
+	history := parallelHistory()
+
+	for _, limit := range []int{0, 1, 25, 500} {
+		serial, err := NewSearchManager(history).Search(context.Background(), SearchOptions{
+			Query: "needle", SearchInContent: true, Limit: limit, Workers: 1,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, workers := range []int{2, 8} {
+			parallel, err := NewSearchManager(history).Search(context.Background(), SearchOptions{
+				Query: "needle", SearchInContent: true, Limit: limit, Workers: workers,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(resultIds(serial), resultIds(parallel)) {
+				t.Errorf("limit %d, %d workers: results differ from serial search", limit, workers)
+			}
+			if limit > 0 && len(parallel) != limit {
+				t.Errorf("limit %d, %d workers: got %d results", limit, workers, len(parallel))
+			}
+		}
+	}
+}
+
+func TestSplitConversation(t *testing.T) {
+	conv := &models.SkypeConversation{MessageList: make([]models.SkypeMessage, 2*searchChunkSize+1)}
+	jobs := splitConversation(conv, SearchOptions{})
+	if len(jobs) != 3 || jobs[2].start != 2*searchChunkSize || jobs[2].end != 2*searchChunkSize+1 {
+		t.Errorf("unexpected jobs: %+v", jobs)
+	}
+
+	if jobs := splitConversation(&models.SkypeConversation{}, SearchOptions{}); len(jobs) != 1 || jobs[0].end != 0 {
+		t.Errorf("expected one empty job, got %+v", jobs)
+	}
+
+	if jobs := splitConversation(conv, SearchOptions{ContextAfter: 1}); len(jobs) != 1 {
+		t.Errorf("expected a whole conversation job when context is requested, got %d jobs", len(jobs))
+	}
+}
+
+func TestSearchManager_ParallelCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	results, err := NewSearchManager(parallelHistory()).Search(ctx, SearchOptions{
+		Query: "needle", SearchInContent: true, Workers: 4,
+	})
+	if err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	if len(results) != 0 {
+		t.Errorf("expected no results, got %d", len(results))
+	}
+}
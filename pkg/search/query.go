@@ -0,0 +1,463 @@
+package search
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/beckxie/skype-history-viewer-cli/pkg/analyzer"
+	"github.com/beckxie/skype-history-viewer-cli/pkg/models"
+	"github.com/beckxie/skype-history-viewer-cli/pkg/utils"
+)
+
+// QueryError reports a syntax error in an advanced query
+type QueryError struct {
+	Query string
+	Pos   int // rune offset of the offending token
+	Msg   string
+}
+
+// Error renders the message followed by the query with a caret under the
+// offending token
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("invalid query at position %d: %s\n  %s\n  %s^",
+		e.Pos+1, e.Msg, e.Query, strings.Repeat(" ", e.Pos))
+}
+
+type tokenKind int
+
+const (
+	tokenWord tokenKind = iota
+	tokenPhrase
+	tokenAnd
+	tokenOr
+	tokenNot
+	tokenLParen
+	tokenRParen
+	tokenEOF
+)
+
+// queryToken is a lexed piece of an advanced query
+type queryToken struct {
+	kind  tokenKind
+	text  string
+	field string // qualifier such as "from" for from:value terms
+	pos   int
+}
+
+// queryFields lists the supported field qualifiers
+var queryFields = map[string]bool{
+	"from":     true,
+	"in":       true,
+	"type":     true,
+	"before":   true,
+	"after":    true,
+	"has":      true,
+	"mentions": true,
+}
+
+// lexQuery splits an advanced query into tokens
+func lexQuery(query string) ([]queryToken, error) {
+	runes := []rune(query)
+	var tokens []queryToken
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, queryToken{kind: tokenLParen, text: "(", pos: i})
+			i++
+		case r == ')':
+			tokens = append(tokens, queryToken{kind: tokenRParen, text: ")", pos: i})
+			i++
+		case r == '"':
+			text, next, err := lexPhrase(query, runes, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, queryToken{kind: tokenPhrase, text: text, pos: i})
+			i = next
+		case r == '-' && i+1 < len(runes) && !unicode.IsSpace(runes[i+1]) && runes[i+1] != ')':
+			// -term is shorthand for NOT term
+			tokens = append(tokens, queryToken{kind: tokenNot, text: "-", pos: i})
+			i++
+		default:
+			start := i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && runes[i] != '(' && runes[i] != ')' && runes[i] != '"' {
+				i++
+			}
+			word := string(runes[start:i])
+
+			switch word {
+			case "AND":
+				tokens = append(tokens, queryToken{kind: tokenAnd, text: word, pos: start})
+				continue
+			case "OR":
+				tokens = append(tokens, queryToken{kind: tokenOr, text: word, pos: start})
+				continue
+			case "NOT":
+				tokens = append(tokens, queryToken{kind: tokenNot, text: word, pos: start})
+				continue
+			}
+
+			token := queryToken{kind: tokenWord, text: word, pos: start}
+			if colon := strings.Index(word, ":"); colon > 0 && queryFields[strings.ToLower(word[:colon])] {
+				token.field = strings.ToLower(word[:colon])
+				token.text = word[colon+1:]
+				if token.text == "" {
+					// from:"John Doe"
+					if i < len(runes) && runes[i] == '"' {
+						text, next, err := lexPhrase(query, runes, i)
+						if err != nil {
+							return nil, err
+						}
+						token.kind = tokenPhrase
+						token.text = text
+						i = next
+					} else {
+						return nil, &QueryError{Query: query, Pos: start, Msg: fmt.Sprintf("missing value for %q", word)}
+					}
+				}
+			}
+			tokens = append(tokens, token)
+		}
+	}
+
+	tokens = append(tokens, queryToken{kind: tokenEOF, pos: len(runes)})
+	return tokens, nil
+}
+
+// lexPhrase reads a quoted phrase starting at runes[start], which is '"'
+func lexPhrase(query string, runes []rune, start int) (string, int, error) {
+	var phrase strings.Builder
+	for i := start + 1; i < len(runes); i++ {
+		switch runes[i] {
+		case '\\':
+			if i+1 < len(runes) {
+				i++
+				phrase.WriteRune(runes[i])
+			}
+		case '"':
+			if phrase.Len() == 0 {
+				return "", 0, &QueryError{Query: query, Pos: start, Msg: "empty phrase"}
+			}
+			return phrase.String(), i + 1, nil
+		default:
+			phrase.WriteRune(runes[i])
+		}
+	}
+	return "", 0, &QueryError{Query: query, Pos: start, Msg: "unterminated quoted phrase"}
+}
+
+// queryContext is the message an advanced query is evaluated against
+type queryContext struct {
+	msg              *models.SkypeMessage
+	conversationName string
+	content          *string
+}
+
+// displayText returns the clean message text, computed once per message
+func (qc *queryContext) displayText() string {
+	if qc.content == nil {
+		content := qc.msg.GetDisplayText()
+		qc.content = &content
+	}
+	return *qc.content
+}
+
+// queryNode is a node of a compiled advanced query
+type queryNode interface {
+	eval(qc *queryContext) bool
+}
+
+type andNode struct{ left, right queryNode }
+
+func (n *andNode) eval(qc *queryContext) bool { return n.left.eval(qc) && n.right.eval(qc) }
+
+type orNode struct{ left, right queryNode }
+
+func (n *orNode) eval(qc *queryContext) bool { return n.left.eval(qc) || n.right.eval(qc) }
+
+type notNode struct{ node queryNode }
+
+func (n *notNode) eval(qc *queryContext) bool { return !n.node.eval(qc) }
+
+// termNode matches free text against the content and sender, as enabled
+// by the search options
+type termNode struct {
+	matcher *matcher
+}
+
+func (n *termNode) eval(qc *queryContext) bool {
+	return n.matchesContent(qc) || n.matchesSender(qc)
+}
+
+func (n *termNode) matchesContent(qc *queryContext) bool {
+	if !n.matcher.options.SearchInContent {
+		return false
+	}
+	_, _, ok := n.matcher.find(qc.displayText())
+	return ok
+}
+
+func (n *termNode) matchesSender(qc *queryContext) bool {
+	if !n.matcher.options.SearchInSender {
+		return false
+	}
+	_, _, ok := n.matcher.find(qc.msg.GetSenderDisplayName())
+	return ok
+}
+
+// predicateNode matches a field qualifier
+type predicateNode struct {
+	test func(qc *queryContext) bool
+}
+
+func (n *predicateNode) eval(qc *queryContext) bool { return n.test(qc) }
+
+// compiledQuery is a parsed advanced query
+type compiledQuery struct {
+	root queryNode
+	// terms holds the free-text terms that are not negated, used to decide
+	// the match type and which span to highlight
+	terms []*termNode
+	// usesOwner is set by mentions:me, which needs the export owner
+	usesOwner bool
+}
+
+// queryParser is a recursive-descent parser over lexed tokens
+type queryParser struct {
+	query     string
+	options   SearchOptions
+	tokens    []queryToken
+	pos       int
+	negated   int
+	terms     []*termNode
+	usesOwner bool
+}
+
+// parseQuery compiles an advanced query. Free-text terms follow the case
+// sensitivity and regex settings of options.
+func parseQuery(options SearchOptions) (*compiledQuery, error) {
+	tokens, err := lexQuery(options.Query)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &queryParser{query: options.Query, options: options, tokens: tokens}
+	if p.peek().kind == tokenEOF {
+		return nil, p.errorf(p.peek(), "empty query")
+	}
+
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if token := p.peek(); token.kind != tokenEOF {
+		if token.kind == tokenRParen {
+			return nil, p.errorf(token, "unmatched closing parenthesis")
+		}
+		return nil, p.errorf(token, fmt.Sprintf("unexpected %q", token.text))
+	}
+
+	return &compiledQuery{root: root, terms: p.terms, usesOwner: p.usesOwner}, nil
+}
+
+func (p *queryParser) peek() queryToken {
+	return p.tokens[p.pos]
+}
+
+func (p *queryParser) next() queryToken {
+	token := p.tokens[p.pos]
+	if token.kind != tokenEOF {
+		p.pos++
+	}
+	return token
+}
+
+func (p *queryParser) errorf(token queryToken, msg string) error {
+	return &QueryError{Query: p.query, Pos: token.pos, Msg: msg}
+}
+
+// parseOr parses: and ( OR and )*
+func (p *queryParser) parseOr() (queryNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek().kind == tokenOr {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &orNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+// parseAnd parses: not ( [AND] not )*, where juxtaposition means AND
+func (p *queryParser) parseAnd() (queryNode, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		switch p.peek().kind {
+		case tokenAnd:
+			p.next()
+		case tokenWord, tokenPhrase, tokenNot, tokenLParen:
+		default:
+			return left, nil
+		}
+
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &andNode{left: left, right: right}
+	}
+}
+
+// parseNot parses: NOT not | primary
+func (p *queryParser) parseNot() (queryNode, error) {
+	if p.peek().kind != tokenNot {
+		return p.parsePrimary()
+	}
+
+	p.next()
+	p.negated++
+	node, err := p.parseNot()
+	p.negated--
+	if err != nil {
+		return nil, err
+	}
+	return &notNode{node: node}, nil
+}
+
+// parsePrimary parses: ( or ) | term
+func (p *queryParser) parsePrimary() (queryNode, error) {
+	token := p.next()
+	switch token.kind {
+	case tokenLParen:
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek().kind != tokenRParen {
+			return nil, p.errorf(token, "missing closing parenthesis")
+		}
+		p.next()
+		return node, nil
+	case tokenWord, tokenPhrase:
+		if token.field != "" {
+			return p.fieldNode(token)
+		}
+		return p.termNode(token)
+	case tokenEOF:
+		return nil, p.errorf(token, "unexpected end of query, expected a search term")
+	case tokenRParen:
+		return nil, p.errorf(token, "unexpected closing parenthesis, expected a search term")
+	default:
+		return nil, p.errorf(token, fmt.Sprintf("expected a search term, got %q", token.text))
+	}
+}
+
+// termNode builds a free-text term
+func (p *queryParser) termNode(token queryToken) (queryNode, error) {
+	termOptions := p.options
+	termOptions.Query = token.text
+	termOptions.AdvancedQuery = false
+
+	m, err := newMatcher(termOptions)
+	if err != nil {
+		return nil, p.errorf(token, err.Error())
+	}
+
+	node := &termNode{matcher: m}
+	if p.negated%2 == 0 {
+		p.terms = append(p.terms, node)
+	}
+	return node, nil
+}
+
+// fieldNode builds a predicate for a field qualifier
+func (p *queryParser) fieldNode(token queryToken) (queryNode, error) {
+	value := analyzer.Fold(token.text, true)
+
+	switch token.field {
+	case "from":
+		return &predicateNode{test: func(qc *queryContext) bool {
+			return strings.Contains(analyzer.Fold(qc.msg.GetSenderDisplayName(), true), value) ||
+				strings.Contains(analyzer.Fold(qc.msg.From, true), value)
+		}}, nil
+	case "in":
+		return &predicateNode{test: func(qc *queryContext) bool {
+			return strings.Contains(analyzer.Fold(qc.conversationName, true), value)
+		}}, nil
+	case "type":
+		return &predicateNode{test: func(qc *queryContext) bool {
+			return strings.Contains(analyzer.Fold(qc.msg.MessageType, true), value)
+		}}, nil
+	case "mentions":
+		// mentions:me stands for the export owner, which checkOwner
+		// requires to be known
+		if value == "me" {
+			p.usesOwner = true
+			ownerId := p.options.OwnerId
+			return &predicateNode{test: func(qc *queryContext) bool {
+				return qc.msg.MentionsUser(ownerId)
+			}}, nil
+		}
+		return &predicateNode{test: func(qc *queryContext) bool {
+			for _, mention := range qc.msg.Mentions() {
+				if strings.Contains(analyzer.Fold(mention.Name, true), value) ||
+					strings.Contains(analyzer.Fold(mention.Id, true), value) {
+					return true
+				}
+			}
+			return false
+		}}, nil
+	case "before", "after":
+		date, err := utils.ParseDateString(token.text)
+		if err != nil {
+			return nil, p.errorf(token, fmt.Sprintf("invalid date %q for %s:", token.text, token.field))
+		}
+		before := token.field == "before"
+		return &predicateNode{test: func(qc *queryContext) bool {
+			t, err := qc.msg.GetTimestamp()
+			if err != nil {
+				return false
+			}
+			if before {
+				return t.Before(*date)
+			}
+			return !t.Before(*date)
+		}}, nil
+	case "has":
+		switch value {
+		case "attachment":
+			return &predicateNode{test: func(qc *queryContext) bool {
+				return qc.msg.HasAttachments()
+			}}, nil
+		case "link":
+			return &predicateNode{test: hasLink}, nil
+		}
+		return nil, p.errorf(token, fmt.Sprintf("unknown has: value %q (expected attachment or link)", token.text))
+	}
+
+	return nil, p.errorf(token, fmt.Sprintf("unknown field %q", token.field))
+}
+
+// hasLink reports whether a message carries a URL preview or a link
+func hasLink(qc *queryContext) bool {
+	if qc.msg.HasUrlPreviews() {
+		return true
+	}
+	content := strings.ToLower(qc.msg.Content)
+	return strings.Contains(content, "http://") || strings.Contains(content, "https://")
+}
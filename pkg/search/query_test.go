@@ -0,0 +1,222 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/beckxie/skype-history-viewer-cli/pkg/models"
+)
+
+func TestSearchManager_AdvancedQuery(t *testing.T) {
+	urlPreviews := `[{"url":"https://example.com"}]`
+	history := &models.SkypeHistoryRoot{
+		UserId: "live:carol",
+		Conversations: []models.SkypeConversation{
+			{
+				Id:          "project",
+				DisplayName: stringPtr("Project Team"),
+				MessageList: []models.SkypeMessage{
+					{OriginalId: "1", Content: "deploy the release today", From: "8:live:alice", DisplayName: stringPtr("Alice"), MessageType: "Text", Timestamp: "2024-01-01T10:00:00Z"},
+					{OriginalId: "2", Content: "release notes are ready", From: "8:live:bob", DisplayName: stringPtr("Bob"), MessageType: "Text", Timestamp: "2024-01-02T10:00:00Z"},
+					{OriginalId: "3", Content: "see https://example.com/notes", From: "8:live:bob", DisplayName: stringPtr("Bob"), MessageType: "Text", Timestamp: "2024-01-03T10:00:00Z"},
+					{OriginalId: "4", Content: "spec.pdf", From: "8:live:alice", DisplayName: stringPtr("Alice"), MessageType: "RichText/Media_GenericFile", Timestamp: "2024-01-04T10:00:00Z", AmsReferences: []string{"ref"}},
+				},
+			},
+			{
+				Id:          "family",
+				DisplayName: stringPtr("Family"),
+				MessageList: []models.SkypeMessage{
+					{OriginalId: "5", Content: "release the hounds", From: "8:live:carol", DisplayName: stringPtr("Carol"), MessageType: "Text", Timestamp: "2024-01-05T10:00:00Z"},
+					{OriginalId: "6", Content: "dinner", From: "8:live:carol", DisplayName: stringPtr("Carol"), MessageType: "Text", Timestamp: "2024-01-06T10:00:00Z", Properties: &models.MessageProperties{UrlPreviews: &urlPreviews}},
+					{OriginalId: "7", Content: `<at id="8:live:carol">Carol</at> are you in?`, From: "8:live:bob", DisplayName: stringPtr("Bob"), MessageType: "RichText", Timestamp: "2024-01-07T10:00:00Z"},
+					{OriginalId: "8", Content: `<at id="*">all</at> standup`, From: "8:live:bob", DisplayName: stringPtr("Bob"), MessageType: "RichText", Timestamp: "2024-01-08T10:00:00Z"},
+				},
+			},
+		},
+	}
+
+	sm := NewSearchManager(history)
+
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{"implicit AND", "release notes", []string{"2"}},
+		{"explicit AND", "release AND today", []string{"1"}},
+		{"OR", "today OR hounds", []string{"1", "5"}},
+		{"NOT", "release NOT notes", []string{"1", "5"}},
+		{"minus shorthand", "release -notes", []string{"1", "5"}},
+		{"phrase", `"release the"`, []string{"5"}},
+		{"parentheses", "(today OR notes) AND release", []string{"1", "2"}},
+		{"precedence", "hounds OR release AND today", []string{"1", "5"}},
+		{"from name", "release from:bob", []string{"2"}},
+		{"from id", "from:live:carol", []string{"5", "6"}},
+		{"from phrase", `from:"ali"`, []string{"1", "4"}},
+		{"in", "release in:family", []string{"5"}},
+		{"type", "type:media_genericfile", []string{"4"}},
+		{"before", "release before:2024-01-02", []string{"1"}},
+		{"after", "release after:2024-01-02", []string{"2", "5"}},
+		{"has attachment", "has:attachment", []string{"4"}},
+		{"has link", "has:link", []string{"3", "6"}},
+		{"mentions", "mentions:carol", []string{"7"}},
+		{"mentions me", "mentions:me", []string{"7", "8"}},
+		{"lowercase operators are terms", "release and today", nil},
+		{"unknown qualifier is a term", "https://example.com", []string{"3"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := sm.Search(context.Background(), SearchOptions{
+				Query:           tt.query,
+				AdvancedQuery:   true,
+				SearchInContent: true,
+				SearchInSender:  true,
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var got []string
+			for _, result := range results {
+				got = append(got, result.Message.OriginalId)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("query %q: expected %v, got %v", tt.query, tt.want, got)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("query %q: expected %v, got %v", tt.query, tt.want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestSearchManager_AdvancedQueryMatchType(t *testing.T) {
+	history := &models.SkypeHistoryRoot{
+		Conversations: []models.SkypeConversation{
+			{
+				MessageList: []models.SkypeMessage{
+					{Content: "hello world", From: "alice", MessageType: "Text", Timestamp: "2024-01-01T10:00:00Z"},
+				},
+			},
+		},
+	}
+	sm := NewSearchManager(history)
+
+	tests := []struct {
+		query     string
+		matchType string
+		context   bool
+	}{
+		{"world", "content", true},
+		{"alice", "sender", false},
+		{"world alice", "both", true},
+		{"from:alice", "filter", false},
+		{"from:alice NOT nothing", "filter", false},
+	}
+
+	for _, tt := range tests {
+		results, err := sm.Search(context.Background(), SearchOptions{
+			Query:           tt.query,
+			AdvancedQuery:   true,
+			SearchInContent: true,
+			SearchInSender:  true,
+		})
+		if err != nil {
+			t.Fatalf("%q: unexpected error: %v", tt.query, err)
+		}
+		if len(results) != 1 {
+			t.Fatalf("%q: expected 1 result, got %d", tt.query, len(results))
+		}
+		if results[0].MatchType != tt.matchType {
+			t.Errorf("%q: expected match type %q, got %q", tt.query, tt.matchType, results[0].MatchType)
+		}
+		if (results[0].MatchContext != "") != tt.context {
+			t.Errorf("%q: unexpected match context %q", tt.query, results[0].MatchContext)
+		}
+	}
+}
+
+func TestParseQueryErrors(t *testing.T) {
+	tests := []struct {
+		query string
+		pos   int
+	}{
+		{"", 0},
+		{"   ", 3},
+		{"foo AND", 7},
+		{"foo OR OR bar", 7},
+		{"(foo bar", 0},
+		{"foo bar)", 7},
+		{"foo ()", 5},
+		{`foo "bar`, 4},
+		{`""`, 0},
+		{"from:", 0},
+		{"foo before:yesterday", 4},
+		{"has:pictures", 0},
+		{"NOT", 3},
+	}
+
+	for _, tt := range tests {
+		_, err := parseQuery(SearchOptions{Query: tt.query})
+		var queryErr *QueryError
+		if !errors.As(err, &queryErr) {
+			t.Errorf("%q: expected QueryError, got %v", tt.query, err)
+			continue
+		}
+		if queryErr.Pos != tt.pos {
+			t.Errorf("%q: expected error at %d, got %d (%v)", tt.query, tt.pos, queryErr.Pos, err)
+		}
+	}
+}
+
+func TestQueryErrorPointsAtToken(t *testing.T) {
+	_, err := parseQuery(SearchOptions{Query: "foo AND )"})
+	if err == nil {
+		t.Fatal("expected parse error")
+	}
+
+	want := "invalid query at position 9: unexpected closing parenthesis, expected a search term\n  foo AND )\n          ^"
+	if err.Error() != want {
+		t.Errorf("unexpected error message:\n%s\nwant:\n%s", err.Error(), want)
+	}
+}
+
+func TestAdvancedQueryRegexTerms(t *testing.T) {
+	history := &models.SkypeHistoryRoot{
+		Conversations: []models.SkypeConversation{
+			{
+				MessageList: []models.SkypeMessage{
+					{Content: "ticket ABC-123", From: "alice", MessageType: "Text", Timestamp: "2024-01-01T10:00:00Z"},
+					{Content: "ticket ABC-x", From: "bob", MessageType: "Text", Timestamp: "2024-01-01T10:00:00Z"},
+				},
+			},
+		},
+	}
+	sm := NewSearchManager(history)
+
+	results, err := sm.Search(context.Background(), SearchOptions{
+		Query:           `ticket abc-\d+`,
+		AdvancedQuery:   true,
+		RegexSearch:     true,
+		SearchInContent: true,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(results) != 1 || results[0].Message.From != "alice" {
+		t.Errorf("expected only alice's message, got %+v", results)
+	}
+
+	if _, err := sm.Search(context.Background(), SearchOptions{
+		Query:           `ok (unclosed`,
+		AdvancedQuery:   true,
+		RegexSearch:     true,
+		SearchInContent: true,
+	}); err == nil {
+		t.Error("expected error for unbalanced query")
+	}
+}
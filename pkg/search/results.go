@@ -0,0 +1,131 @@
+package search
+
+import (
+	"context"
+	"iter"
+
+	"github.com/beckxie/skype-history-viewer-cli/pkg/progress"
+	"github.com/beckxie/skype-history-viewer-cli/pkg/viewer"
+)
+
+// Results searches the loaded history like Search, but yields each result
+// as soon as it is known instead of returning them all at the end.
+// Breaking out of the loop stops the search.
+//
+// Results come in export order. Orders that need every result first, date
+// order and fuzzy distance, are applied before the first one is yielded.
+// A failing search yields a single error, after the results found so far.
+func (sm *SearchManager) Results(ctx context.Context, options SearchOptions) iter.Seq2[viewer.SearchResult, error] {
+	return func(yield func(viewer.SearchResult, error) bool) {
+		if options.OwnerId == "" {
+			options.OwnerId = sm.history.UserId
+		}
+		reporter := progress.Or(options.Reporter)
+		sm.yieldResults(ctx, options, sm.buildCacheKey(options), sm.historyJobs(options), sm.historyProgress(reporter), yield)
+	}
+}
+
+// StreamResults searches conversations as they are read from stream like
+// SearchStream, yielding each result as soon as it is known. It behaves
+// like Results; the stream can only be iterated once.
+func (sm *SearchManager) StreamResults(ctx context.Context, stream ConversationReader, options SearchOptions) iter.Seq2[viewer.SearchResult, error] {
+	return func(yield func(viewer.SearchResult, error) bool) {
+		if err := streamOwner(stream, &options); err != nil {
+			yield(viewer.SearchResult{}, err)
+			return
+		}
+		reporter := progress.Or(options.Reporter)
+		sm.yieldResults(ctx, options, sm.buildCacheKey(options), streamJobs(stream, options, streamProgress(stream, reporter)), nil, yield)
+	}
+}
+
+// yieldResults runs a search on the workers and hands its results to yield
+// from the calling goroutine. Complete searches are cached.
+func (sm *SearchManager) yieldResults(ctx context.Context, options SearchOptions, cacheKey string, next func() (searchJob, error), onMessage func(), yield func(viewer.SearchResult, error) bool) {
+	if cached, ok := sm.cache.get(cacheKey); ok {
+		for _, result := range cached {
+			if !yield(result, nil) {
+				return
+			}
+		}
+		return
+	}
+
+	m, err := newMatcher(options)
+	if err == nil {
+		err = checkOwner(m)
+	}
+	if err != nil {
+		yield(viewer.SearchResult{}, err)
+		return
+	}
+
+	defer progress.Or(options.Reporter).SearchFinished()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Sorted orders can't be produced incrementally
+	if sortedOrder(options) {
+		results, err := sm.collectParallel(ctx, m, next, onMessage)
+		if err != nil {
+			for _, result := range results {
+				if !yield(result, nil) {
+					return
+				}
+			}
+			yield(viewer.SearchResult{}, err)
+			return
+		}
+		sortResults(results, options)
+		results = limitResults(results, options)
+		sm.cache.put(cacheKey, results)
+		for _, result := range results {
+			if !yield(result, nil) {
+				return
+			}
+		}
+		return
+	}
+
+	// The workers hand over batches in export order; yield runs here
+	batches := make(chan []viewer.SearchResult)
+	var searchErr error
+	go func() {
+		defer close(batches)
+		searchErr = sm.searchParallel(ctx, m, next, onMessage, func(batch []viewer.SearchResult) bool {
+			select {
+			case batches <- batch:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		})
+	}()
+
+	results := []viewer.SearchResult{}
+	stopped := false
+	for batch := range batches {
+		if stopped {
+			// Drain until the workers notice the cancellation
+			continue
+		}
+		for _, result := range batch {
+			if !yield(result, nil) {
+				stopped = true
+				cancel()
+				break
+			}
+		}
+		results = append(results, batch...)
+	}
+	if stopped {
+		return
+	}
+
+	if searchErr != nil {
+		yield(viewer.SearchResult{}, searchErr)
+		return
+	}
+	sm.cache.put(cacheKey, results)
+}
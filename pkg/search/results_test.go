@@ -0,0 +1,166 @@
+package search
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/beckxie/skype-history-viewer-cli/pkg/utils"
+	"github.com/beckxie/skype-history-viewer-cli/pkg/viewer"
+)
+
+func TestSearchManager_Results(t *testing.T) {
+	history := parallelHistory()
+	options := SearchOptions{Query: "needle", SearchInContent: true, Limit: 500, Workers: 4}
+
+	want, err := NewSearchManager(history).Search(context.Background(), options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sm := NewSearchManager(history)
+	var got []viewer.SearchResult
+	for result, err := range sm.Results(context.Background(), options) {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		got = append(got, result)
+	}
+	if !reflect.DeepEqual(resultIds(got), resultIds(want)) {
+		t.Error("expected the same results as Search")
+	}
+
+	// A complete iteration is cached
+	if _, ok := sm.cache.get(sm.buildCacheKey(options)); !ok {
+		t.Error("expected the results to be cached")
+	}
+}
+
+func TestSearchManager_ResultsStopEarly(t *testing.T) {
+	sm := NewSearchManager(parallelHistory())
+	options := SearchOptions{Query: "needle", SearchInContent: true, Workers: 4}
+
+	var got []string
+	for result, err := range sm.Results(context.Background(), options) {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		got = append(got, result.Message.OriginalId)
+		if len(got) == 3 {
+			break
+		}
+	}
+
+	if !reflect.DeepEqual(got, []string{"c0-m0", "c0-m7", "c0-m14"}) {
+		t.Errorf("unexpected first results: %v", got)
+	}
+	if sm.cache.len() != 0 {
+		t.Error("expected an abandoned search not to be cached")
+	}
+}
+
+func TestSearchManager_ResultsErrors(t *testing.T) {
+	sm := NewSearchManager(parallelHistory())
+
+	var errs []error
+	for _, err := range sm.Results(context.Background(), SearchOptions{Query: "(", RegexSearch: true, SearchInContent: true}) {
+		errs = append(errs, err)
+	}
+	if len(errs) != 1 || errs[0] == nil {
+		t.Errorf("expected a single error for an invalid regex, got %v", errs)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var last error
+	for _, err := range sm.Results(ctx, SearchOptions{Query: "needle", SearchInContent: true}) {
+		last = err
+	}
+	if last != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", last)
+	}
+}
+
+func TestSearchManager_StreamResults(t *testing.T) {
+	jsonContent := `{"userId": "u", "conversations": [
+		{"id": "a", "displayName": "First", "MessageList": [
+			{"id": "m1", "content": "apple pie", "messagetype": "Text", "originalarrivaltime": "2024-01-02T10:00:00Z"},
+			{"id": "m2", "content": "banana", "messagetype": "Text", "originalarrivaltime": "2024-01-01T10:01:00Z"}
+		]},
+		{"id": "b", "displayName": "Second", "MessageList": [
+			{"id": "m3", "content": "apple juice", "messagetype": "Text", "originalarrivaltime": "2024-01-01T10:02:00Z"}
+		]}
+	]}`
+
+	for _, tt := range []struct {
+		sort string
+		want []string
+	}{
+		{"", []string{"m1", "m3"}},
+		{SortDate, []string{"m3", "m1"}},
+	} {
+		sm := NewSearchManager(nil)
+		stream := utils.NewHistoryStream(strings.NewReader(jsonContent))
+
+		var got []string
+		for result, err := range sm.StreamResults(context.Background(), stream, SearchOptions{Query: "apple", SearchInContent: true, Sort: tt.sort}) {
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got = append(got, result.Message.OriginalId)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("sort %q: expected %v, got %v", tt.sort, tt.want, got)
+		}
+	}
+
+	// Decoding errors are yielded after the results read so far
+	sm := NewSearchManager(nil)
+	stream := utils.NewHistoryStream(strings.NewReader(`{"conversations": [{"id": "a", "MessageList": [{"id": "m1", "content": "apple", "messagetype": "Text"}]}, {`))
+	var ids []string
+	var last error
+	for result, err := range sm.StreamResults(context.Background(), stream, SearchOptions{Query: "apple", SearchInContent: true}) {
+		if err != nil {
+			last = err
+			continue
+		}
+		ids = append(ids, result.Message.OriginalId)
+	}
+	if !reflect.DeepEqual(ids, []string{"m1"}) || last == nil {
+		t.Errorf("expected m1 then an error, got %v and %v", ids, last)
+	}
+}
+
+func TestSearchManager_StreamResultsOwner(t *testing.T) {
+	jsonContent := `{"userId": "live:carol", "conversations": [
+		{"id": "a", "MessageList": [
+			{"id": "m1", "content": "apple pie", "from": "8:live:carol", "messagetype": "Text", "originalarrivaltime": "2024-01-01T10:00:00Z"},
+			{"id": "m2", "content": "<at id=\"8:live:carol\">Carol</at> apple?", "from": "8:live:bob", "messagetype": "Text", "originalarrivaltime": "2024-01-01T10:01:00Z"}
+		]}
+	]}`
+	mine := true
+
+	for _, tt := range []struct {
+		name    string
+		options SearchOptions
+		want    []string
+	}{
+		{"own messages", SearchOptions{Query: "apple", SearchInContent: true, FromOwner: &mine}, []string{"m1"}},
+		{"mentions of the owner", SearchOptions{Query: "mentions:me", AdvancedQuery: true}, []string{"m2"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			stream := utils.NewHistoryStream(strings.NewReader(jsonContent))
+			var got []string
+			for result, err := range NewSearchManager(nil).StreamResults(context.Background(), stream, tt.options) {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				got = append(got, result.Message.OriginalId)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package search
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// MaxHistory is how many past searches the history keeps
+const MaxHistory = 50
+
+// SavedSearch is a search stored under a name so that it can be run again
+type SavedSearch struct {
+	Name    string        `json:"name"`
+	Options SearchOptions `json:"options"`
+	SavedAt time.Time     `json:"saved_at"`
+}
+
+// HistoryEntry is a search that was run against an export
+type HistoryEntry struct {
+	Options SearchOptions `json:"options"`
+	Export  string        `json:"export"`
+	RanAt   time.Time     `json:"ran_at"`
+}
+
+// Store keeps the saved searches and the search history of a user
+type Store struct {
+	Saved   []SavedSearch  `json:"saved"`   // ordered by name
+	History []HistoryEntry `json:"history"` // most recent first
+
+	path string
+}
+
+// StorePath returns where saved searches and history are kept, in the
+// user's config directory
+func StorePath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate config directory: %w", err)
+	}
+	return filepath.Join(configDir, "skype-history-viewer-cli", "searches.json"), nil
+}
+
+// LoadStore reads the store at path, returning an empty one when the file
+// does not exist yet
+func LoadStore(path string) (*Store, error) {
+	store := &Store{path: path}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read saved searches: %w", err)
+	}
+	if err := json.Unmarshal(data, store); err != nil {
+		return nil, fmt.Errorf("failed to parse saved searches: %w", err)
+	}
+	return store, nil
+}
+
+// Write persists the store where it was loaded from
+func (s *Store) Write() error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode saved searches: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	// Write to a temporary file first so that a crash never loses the store
+	tmpFile, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed to write saved searches: %w", err)
+	}
+	defer os.Remove(tmpFile.Name())
+
+	if _, err := tmpFile.Write(data); err != nil {
+		tmpFile.Close()
+		return fmt.Errorf("failed to write saved searches: %w", err)
+	}
+	if err := tmpFile.Close(); err != nil {
+		return fmt.Errorf("failed to write saved searches: %w", err)
+	}
+	if err := os.Rename(tmpFile.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to write saved searches: %w", err)
+	}
+	return nil
+}
+
+// Find returns the search saved under name
+func (s *Store) Find(name string) (SavedSearch, bool) {
+	i, found := s.find(name)
+	if !found {
+		return SavedSearch{}, false
+	}
+	return s.Saved[i], true
+}
+
+// SaveSearch stores options under name, replacing any search saved under
+// the same name
+func (s *Store) SaveSearch(name string, options SearchOptions, at time.Time) {
+	saved := SavedSearch{Name: name, Options: storable(options), SavedAt: at}
+
+	i, found := s.find(name)
+	if found {
+		s.Saved[i] = saved
+		return
+	}
+	s.Saved = slices.Insert(s.Saved, i, saved)
+}
+
+// Record adds a search to the front of the history. Running the same
+// search again only moves it to the front.
+func (s *Store) Record(options SearchOptions, export string, at time.Time) {
+	entry := HistoryEntry{Options: storable(options), Export: export, RanAt: at}
+
+	s.History = slices.DeleteFunc(s.History, func(e HistoryEntry) bool {
+		return e.Export == entry.Export && sameOptions(e.Options, entry.Options)
+	})
+	s.History = slices.Insert(s.History, 0, entry)
+	if len(s.History) > MaxHistory {
+		s.History = s.History[:MaxHistory]
+	}
+}
+
+// find returns the position of name among the saved searches, or where it
+// would be inserted
+func (s *Store) find(name string) (int, bool) {
+	return slices.BinarySearchFunc(s.Saved, name, func(saved SavedSearch, name string) int {
+		return strings.Compare(saved.Name, name)
+	})
+}
+
+// storable drops the fields that only make sense for the current run
+func storable(options SearchOptions) SearchOptions {
+	options.Workers = 0
+	options.Reporter = nil
+	options.OwnerId = ""
+	return options
+}
+
+// sameOptions reports whether two options are stored the same way
+func sameOptions(a, b SearchOptions) bool {
+	aData, aErr := json.Marshal(a)
+	bData, bErr := json.Marshal(b)
+	return aErr == nil && bErr == nil && string(aData) == string(bData)
+}
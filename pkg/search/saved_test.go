@@ -0,0 +1,123 @@
+package search
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestStoreRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config", "searches.json")
+
+	// A missing file is an empty store
+	store, err := LoadStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(store.Saved) != 0 || len(store.History) != 0 {
+		t.Fatalf("expected an empty store, got %+v", store)
+	}
+
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	mine := true
+	options := SearchOptions{
+		Query:              "invoice",
+		SearchInContent:    true,
+		ConversationFilter: "Finance",
+		DateFrom:           &from,
+		Limit:              20,
+		MessageTypes:       []string{"RichText"},
+		FromOwner:          &mine,
+		Workers:            8,
+		OwnerId:            "me",
+	}
+	at := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	store.SaveSearch("weekly", options, at)
+	store.Record(options, "/exports/messages.json", at)
+	if err := store.Write(); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved, ok := loaded.Find("weekly")
+	if !ok {
+		t.Fatal("expected the saved search to be found")
+	}
+
+	// Only the criteria are kept
+	want := options
+	want.Workers = 0
+	want.OwnerId = ""
+	if !reflect.DeepEqual(saved.Options, want) {
+		t.Errorf("expected %+v, got %+v", want, saved.Options)
+	}
+	if !saved.SavedAt.Equal(at) {
+		t.Errorf("expected saved at %v, got %v", at, saved.SavedAt)
+	}
+	if len(loaded.History) != 1 || loaded.History[0].Export != "/exports/messages.json" {
+		t.Errorf("unexpected history %+v", loaded.History)
+	}
+}
+
+func TestStoreSaveSearch(t *testing.T) {
+	store := &Store{}
+	at := time.Now()
+	store.SaveSearch("b", SearchOptions{Query: "first"}, at)
+	store.SaveSearch("a", SearchOptions{Query: "second"}, at)
+	store.SaveSearch("b", SearchOptions{Query: "third"}, at)
+
+	var names, queries []string
+	for _, saved := range store.Saved {
+		names = append(names, saved.Name)
+		queries = append(queries, saved.Options.Query)
+	}
+	if !reflect.DeepEqual(names, []string{"a", "b"}) || !reflect.DeepEqual(queries, []string{"second", "third"}) {
+		t.Errorf("expected a=second and b=third, got %v %v", names, queries)
+	}
+
+	if _, ok := store.Find("c"); ok {
+		t.Error("expected no search named c")
+	}
+}
+
+func TestStoreRecord(t *testing.T) {
+	store := &Store{}
+	at := time.Now()
+	for i := 0; i < MaxHistory+5; i++ {
+		store.Record(SearchOptions{Query: string(rune('a' + i%26)), Limit: i}, "export", at)
+	}
+	if len(store.History) != MaxHistory {
+		t.Fatalf("expected %d entries, got %d", MaxHistory, len(store.History))
+	}
+	if store.History[0].Options.Limit != MaxHistory+4 {
+		t.Errorf("expected the most recent search first, got %+v", store.History[0].Options)
+	}
+
+	// Running a search again moves it to the front
+	again := store.History[3].Options
+	store.Record(again, "export", at)
+	if len(store.History) != MaxHistory || !sameOptions(store.History[0].Options, again) {
+		t.Errorf("expected the repeated search at the front without duplicates")
+	}
+
+	// The same search on another export is a separate entry
+	store.Record(again, "other", at)
+	if len(store.History) != MaxHistory || store.History[0].Export != "other" || !sameOptions(store.History[1].Options, again) {
+		t.Errorf("expected a separate entry per export")
+	}
+}
+
+func TestLoadStoreCorrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "searches.json")
+	if err := os.WriteFile(path, []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadStore(path); err == nil {
+		t.Error("expected an error for a corrupt store")
+	}
+}
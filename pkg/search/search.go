@@ -2,178 +2,317 @@ package search
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
-	"strings"
-	"sync"
+	"io"
+	"sync/atomic"
 	"time"
 
+	"github.com/beckxie/skype-history-viewer-cli/pkg/analyzer"
 	"github.com/beckxie/skype-history-viewer-cli/pkg/models"
+	"github.com/beckxie/skype-history-viewer-cli/pkg/progress"
 	"github.com/beckxie/skype-history-viewer-cli/pkg/viewer"
-	"github.com/fatih/color"
 )
 
-// SearchManager handles searching through Skype history
+// progressInterval is how many messages are searched between two progress
+// reports, so that workers rarely contend on the reporter
+const progressInterval = 256
+
+// SearchManager handles searching through Skype history. Its result cache
+// belongs to a single export, whichever way that export is searched.
 type SearchManager struct {
-	history     *models.SkypeHistoryRoot
-	searchCache map[string][]viewer.SearchResult
-	cacheMutex  sync.RWMutex
+	history *models.SkypeHistoryRoot
+	cache   *resultCache
 }
 
 // NewSearchManager creates a new search manager
 func NewSearchManager(history *models.SkypeHistoryRoot) *SearchManager {
 	return &SearchManager{
-		history:     history,
-		searchCache: make(map[string][]viewer.SearchResult),
+		history: history,
+		cache:   newResultCache(DefaultCacheSize),
 	}
 }
 
-// SearchOptions contains search parameters
+// SearchOptions contains search parameters. They serialize to JSON so that
+// searches can be saved and recorded in the search history.
 type SearchOptions struct {
-	Query              string
-	SearchInContent    bool
-	SearchInSender     bool
-	CaseSensitive      bool
-	RegexSearch        bool
-	ConversationFilter string
-	DateFrom           *time.Time
-	DateTo             *time.Time
-	Limit              int
+	Query              string            `json:"query"`
+	SearchInContent    bool              `json:"search_in_content"`
+	SearchInSender     bool              `json:"search_in_sender"`
+	CaseSensitive      bool              `json:"case_sensitive,omitempty"`
+	RegexSearch        bool              `json:"regex,omitempty"`
+	AdvancedQuery      bool              `json:"advanced,omitempty"`     // parse Query with the boolean query language
+	Fuzzy              bool              `json:"fuzzy,omitempty"`        // match words within MaxDistance edits
+	MaxDistance        int               `json:"max_distance,omitempty"` // edits allowed per word, picked by word length when 0
+	ConversationFilter string            `json:"conversation,omitempty"`
+	DateFrom           *time.Time        `json:"date_from,omitempty"`
+	DateTo             *time.Time        `json:"date_to,omitempty"`
+	Limit              int               `json:"limit"`
+	Sort               string            `json:"sort,omitempty"`             // SortRelevance, SortDate or "" for export order
+	ContextWidth       int               `json:"context_width,omitempty"`    // characters shown around matches, DefaultContextWidth when 0
+	Workers            int               `json:"-"`                          // parallel searchers, one per CPU when 0
+	ContextBefore      int               `json:"context_before,omitempty"`   // messages shown before each hit
+	ContextAfter       int               `json:"context_after,omitempty"`    // messages shown after each hit
+	Reporter           progress.Reporter `json:"-"`                          // receives search progress, silent when nil
+	MessageTypes       []string          `json:"message_types,omitempty"`    // exact message types to keep, any when empty
+	HasAttachments     bool              `json:"has_attachments,omitempty"`  // only messages with media references
+	HasUrlPreviews     bool              `json:"has_url_previews,omitempty"` // only messages with link previews
+	SenderIds          []string          `json:"sender_ids,omitempty"`       // exact sender ids to keep, any when empty
+	FromOwner          *bool             `json:"from_owner,omitempty"`       // only the owner's messages when true, only others' when false
+	OwnerId            string            `json:"-"`                          // export owner, taken from the history by Search
 }
 
 // Search performs a search across all conversations
 func (sm *SearchManager) Search(ctx context.Context, options SearchOptions) ([]viewer.SearchResult, error) {
+	if options.OwnerId == "" {
+		options.OwnerId = sm.history.UserId
+	}
+
 	// Check cache
 	cacheKey := sm.buildCacheKey(options)
-	sm.cacheMutex.RLock()
-	if cached, ok := sm.searchCache[cacheKey]; ok {
-		sm.cacheMutex.RUnlock()
+	if cached, ok := sm.cache.get(cacheKey); ok {
 		return cached, nil
 	}
-	sm.cacheMutex.RUnlock()
 
-	// Perform search
-	results := []viewer.SearchResult{}
-	totalMessages := 0
-	searchedMessages := 0
+	m, err := newMatcher(options)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkOwner(m); err != nil {
+		return nil, err
+	}
+
+	reporter := progress.Or(options.Reporter)
+	defer reporter.SearchFinished()
 
-	// Count total messages for progress
-	for _, conv := range sm.history.Conversations {
-		totalMessages += len(conv.MessageList)
+	// Fan the conversations out to the workers
+	results, err := sm.collectParallel(ctx, m, sm.historyJobs(options), sm.historyProgress(reporter))
+	if err != nil {
+		return results, err
 	}
 
-	// Progress indicator
-	progressChan := make(chan float64)
-	go sm.showProgress(ctx, progressChan, totalMessages)
+	sortResults(results, options)
+	results = limitResults(results, options)
 
-	// Search through conversations
-	for _, conv := range sm.history.Conversations {
-		// Filter by conversation if specified
-		if options.ConversationFilter != "" {
-			convName := conv.GetConversationDisplayName()
-			if !strings.Contains(strings.ToLower(convName), strings.ToLower(options.ConversationFilter)) {
-				searchedMessages += len(conv.MessageList)
-
-				select {
-				case <-ctx.Done():
-					close(progressChan)
-					return results, ctx.Err()
-				case progressChan <- float64(searchedMessages):
-				default:
-				}
-				continue
+	// Cache results
+	sm.cache.put(cacheKey, results)
+
+	return results, nil
+}
+
+// ConversationReader yields conversations one at a time and returns io.EOF
+// once exhausted. utils.HistoryStream and index.Reader implement it.
+type ConversationReader interface {
+	Next() (*models.SkypeConversation, error)
+	BytesRead() int64
+	Size() int64
+}
+
+// ownerReader is implemented by readers that can name the owner of the
+// export before the first conversation is read
+type ownerReader interface {
+	Owner() (string, error)
+}
+
+// streamOwner fills options.OwnerId from stream when it isn't set and the
+// search needs it: --mine, --others and mentions:me refer to the owner.
+// Other searches leave the stream untouched, so cached ones never read it.
+func streamOwner(stream ConversationReader, options *SearchOptions) error {
+	reader, ok := stream.(ownerReader)
+	if options.OwnerId != "" || !ok || (options.FromOwner == nil && !options.AdvancedQuery) {
+		return nil
+	}
+	owner, err := reader.Owner()
+	if err != nil {
+		return fmt.Errorf("failed to read export header: %w", err)
+	}
+	options.OwnerId = owner
+	return nil
+}
+
+// SearchStream searches conversations as they are read, holding only the
+// conversation currently being searched in memory. Cached results are
+// returned without reading the stream at all. The owner of the export is
+// taken from the stream when options don't name it.
+func (sm *SearchManager) SearchStream(ctx context.Context, stream ConversationReader, options SearchOptions) ([]viewer.SearchResult, error) {
+	if err := streamOwner(stream, &options); err != nil {
+		return nil, err
+	}
+
+	cacheKey := sm.buildCacheKey(options)
+	if cached, ok := sm.cache.get(cacheKey); ok {
+		return cached, nil
+	}
+
+	m, err := newMatcher(options)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkOwner(m); err != nil {
+		return nil, err
+	}
+
+	reporter := progress.Or(options.Reporter)
+	defer reporter.SearchFinished()
+
+	// Conversations are decoded in order and searched by the workers
+	results, err := sm.collectParallel(ctx, m, streamJobs(stream, options, streamProgress(stream, reporter)), nil)
+	if err != nil {
+		return results, err
+	}
+
+	sortResults(results, options)
+	results = limitResults(results, options)
+	sm.cache.put(cacheKey, results)
+
+	return results, nil
+}
+
+// historyJobs returns the jobs covering the loaded history, one at a time
+func (sm *SearchManager) historyJobs(options SearchOptions) func() (searchJob, error) {
+	var jobs []searchJob
+	for i := range sm.history.Conversations {
+		jobs = append(jobs, splitConversation(&sm.history.Conversations[i], options)...)
+	}
+	return func() (searchJob, error) {
+		if len(jobs) == 0 {
+			return searchJob{}, io.EOF
+		}
+		job := jobs[0]
+		jobs = jobs[1:]
+		return job, nil
+	}
+}
+
+// streamJobs returns jobs decoded from stream as the workers need them.
+// onRead, when set, is called after each conversation is read.
+func streamJobs(stream ConversationReader, options SearchOptions, onRead func()) func() (searchJob, error) {
+	var pending []searchJob
+	return func() (searchJob, error) {
+		for len(pending) == 0 {
+			conv, err := stream.Next()
+			if err != nil {
+				return searchJob{}, err
+			}
+			pending = splitConversation(conv, options)
+			if onRead != nil {
+				onRead()
 			}
 		}
+		job := pending[0]
+		pending = pending[1:]
+		return job, nil
+	}
+}
 
-		// Search in messages
-		for _, msg := range conv.MessageList {
-			searchedMessages++
+// searchConversation appends the matches found in conv.MessageList[start:end]
+// to results and reports whether the result limit has been reached
+func (sm *SearchManager) searchConversation(ctx context.Context, conv *models.SkypeConversation, start, end int, m *matcher, results *[]viewer.SearchResult, onMessage func()) (bool, error) {
+	options := m.options
+
+	// Filter by conversation if specified
+	if options.ConversationFilter != "" {
+		if !analyzer.Contains(conv.GetConversationDisplayName(), options.ConversationFilter) {
+			if onMessage != nil {
+				for range end - start {
+					onMessage()
+				}
+			}
 
 			select {
 			case <-ctx.Done():
-				close(progressChan)
-				return results, ctx.Err()
-			case progressChan <- float64(searchedMessages):
+				return false, ctx.Err()
 			default:
 			}
+			return false, nil
+		}
+	}
+
+	// Search in messages
+	for i := start; i < end; i++ {
+		msg := &conv.MessageList[i]
 
-			// Skip system messages
-			if msg.IsSystemMessage() {
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		default:
+		}
+
+		if onMessage != nil {
+			onMessage()
+		}
+
+		// Skip system messages
+		if msg.IsSystemMessage() {
+			continue
+		}
+
+		// Apply date filters
+		if options.DateFrom != nil || options.DateTo != nil {
+			t, err := msg.GetTimestamp()
+			if err != nil {
 				continue
 			}
 
-			// Apply date filters
-			if options.DateFrom != nil || options.DateTo != nil {
-				t, err := msg.GetTimestamp()
-				if err != nil {
-					continue
-				}
+			if options.DateFrom != nil && t.Before(*options.DateFrom) {
+				continue
+			}
+			if options.DateTo != nil && t.After(*options.DateTo) {
+				continue
+			}
+		}
 
-				if options.DateFrom != nil && t.Before(*options.DateFrom) {
-					continue
-				}
-				if options.DateTo != nil && t.After(*options.DateTo) {
-					continue
-				}
+		if hasMessageFilters(options) && !keepMessage(msg, options) {
+			continue
+		}
+
+		// Check for match
+		var matchResult *viewer.SearchResult
+		switch {
+		case m.query != nil:
+			matchResult = sm.checkQuery(msg, conv.GetConversationDisplayName(), m)
+		case m.fuzzy != nil:
+			matchResult = sm.checkFuzzy(msg, m)
+		default:
+			matchResult = sm.checkMatch(msg, m)
+		}
+		if matchResult != nil {
+			matchResult.ConversationId = conv.Id
+			matchResult.ConversationName = conv.GetConversationDisplayName()
+			if options.ContextBefore > 0 || options.ContextAfter > 0 {
+				matchResult.Before, matchResult.After = conv.SurroundingMessages(i, options.ContextBefore, options.ContextAfter)
 			}
+			*results = append(*results, *matchResult)
 
-			// Check for match
-			matchResult := sm.checkMatch(&msg, options)
-			if matchResult != nil {
-				matchResult.ConversationName = conv.GetConversationDisplayName()
-				results = append(results, *matchResult)
-
-				// Check limit
-				if options.Limit > 0 && len(results) >= options.Limit {
-					close(progressChan)
-					sm.cacheResults(cacheKey, results)
-					return results, nil
-				}
+			// Check limit, which sorted orders apply after the scan
+			if options.Limit > 0 && !sortedOrder(options) && len(*results) >= options.Limit {
+				return true, nil
 			}
 		}
 	}
 
-	close(progressChan)
-
-	// Cache results
-	sm.cacheResults(cacheKey, results)
-
-	return results, nil
+	return false, nil
 }
 
 // checkMatch checks if a message matches search criteria
-func (sm *SearchManager) checkMatch(msg *models.SkypeMessage, options SearchOptions) *viewer.SearchResult {
-	query := options.Query
-	if !options.CaseSensitive {
-		query = strings.ToLower(query)
-	}
-
+func (sm *SearchManager) checkMatch(msg *models.SkypeMessage, m *matcher) *viewer.SearchResult {
 	contentMatch := false
 	senderMatch := false
 	matchContext := ""
 
 	// Search in content
-	if options.SearchInContent {
+	if m.options.SearchInContent {
 		content := msg.GetDisplayText()
-		contentToSearch := content
-		if !options.CaseSensitive {
-			contentToSearch = strings.ToLower(contentToSearch)
-		}
-
-		if strings.Contains(contentToSearch, query) {
+		if matches := m.findAll(content); len(matches) > 0 {
 			contentMatch = true
-			// Extract context around match
-			matchContext = sm.extractContext(content, options.Query, options.CaseSensitive, 50)
+			// Extract context around the matches
+			matchContext = sm.extractContext(content, matches, m.options.ContextWidth)
 		}
 	}
 
 	// Search in sender
-	if options.SearchInSender {
-		sender := msg.GetSenderDisplayName()
-		if !options.CaseSensitive {
-			sender = strings.ToLower(sender)
-		}
-
-		if strings.Contains(sender, query) {
+	if m.options.SearchInSender {
+		if _, _, ok := m.find(msg.GetSenderDisplayName()); ok {
 			senderMatch = true
 		}
 	}
@@ -199,131 +338,115 @@ func (sm *SearchManager) checkMatch(msg *models.SkypeMessage, options SearchOpti
 	return nil
 }
 
-// extractContext extracts text around the match
-func (sm *SearchManager) extractContext(text, query string, caseSensitive bool, contextSize int) string {
-	searchText := text
-	searchQuery := query
-	if !caseSensitive {
-		searchText = strings.ToLower(searchText)
-		searchQuery = strings.ToLower(searchQuery)
+// checkQuery evaluates an advanced query against a message. The context
+// highlights every non-negated term found in the content.
+func (sm *SearchManager) checkQuery(msg *models.SkypeMessage, conversationName string, m *matcher) *viewer.SearchResult {
+	qc := &queryContext{msg: msg, conversationName: conversationName}
+	if !m.query.root.eval(qc) {
+		return nil
 	}
 
-	index := strings.Index(searchText, searchQuery)
-	if index == -1 {
-		return ""
-	}
+	contentMatch := false
+	senderMatch := false
+	matchContext := ""
 
-	start := index - contextSize
-	if start < 0 {
-		start = 0
+	var matches []span
+	for _, term := range m.query.terms {
+		if term.matchesContent(qc) {
+			contentMatch = true
+			matches = append(matches, term.matcher.findAll(qc.displayText())...)
+		}
+		if !senderMatch && term.matchesSender(qc) {
+			senderMatch = true
+		}
 	}
-
-	end := index + len(query) + contextSize
-	if end > len(text) {
-		end = len(text)
+	if len(matches) > 0 {
+		matchContext = sm.extractContext(qc.displayText(), matches, m.options.ContextWidth)
 	}
 
-	context := text[start:end]
-
-	// Add ellipsis if truncated
-	if start > 0 {
-		context = "..." + context
-	}
-	if end < len(text) {
-		context = context + "..."
+	matchType := "filter"
+	switch {
+	case contentMatch && senderMatch:
+		matchType = "both"
+	case contentMatch:
+		matchType = "content"
+	case senderMatch:
+		matchType = "sender"
 	}
 
-	matchStart := index - start
-	matchEnd := matchStart + len(query)
-	if matchStart < 0 || matchEnd > len(context) {
-		return context
+	return &viewer.SearchResult{
+		Message:      *msg,
+		MatchContext: matchContext,
+		MatchType:    matchType,
 	}
-	highlighted := context[:matchStart] +
-		color.New(color.FgYellow, color.Bold).Sprint(context[matchStart:matchEnd]) +
-		context[matchEnd:]
+}
 
-	return highlighted
+// extractContext renders the matches found in text with up to
+// contextWidth characters around each, or DefaultContextWidth when it is
+// not positive
+func (sm *SearchManager) extractContext(text string, matches []span, contextWidth int) string {
+	if contextWidth <= 0 {
+		contextWidth = DefaultContextWidth
+	}
+	return buildSnippet(text, matches, contextWidth)
 }
 
-// buildCacheKey creates a unique key for caching
+// buildCacheKey creates a unique key for caching. Options are encoded as
+// JSON, which keeps user strings such as the query from running into the
+// other fields, along with the owner they were resolved against.
 func (sm *SearchManager) buildCacheKey(options SearchOptions) string {
-	parts := []string{
-		options.Query,
-		fmt.Sprintf("%v", options.SearchInContent),
-		fmt.Sprintf("%v", options.SearchInSender),
-		fmt.Sprintf("%v", options.CaseSensitive),
-		fmt.Sprintf("%v", options.RegexSearch),
-		options.ConversationFilter,
-		fmt.Sprintf("%d", options.Limit),
-	}
+	key, _ := json.Marshal(struct {
+		SearchOptions
+		OwnerId string `json:"owner_id"`
+	}{options, options.OwnerId})
+	return string(key)
+}
 
-	if options.DateFrom != nil {
-		parts = append(parts, options.DateFrom.Format(time.RFC3339))
-	}
-	if options.DateTo != nil {
-		parts = append(parts, options.DateTo.Format(time.RFC3339))
+// historyProgress returns a callback, safe for concurrent use, counting the
+// searched messages of the loaded history and reporting them now and then
+func (sm *SearchManager) historyProgress(reporter progress.Reporter) func() {
+	var totalMessages int64
+	for _, conv := range sm.history.Conversations {
+		totalMessages += int64(len(conv.MessageList))
 	}
 
-	return strings.Join(parts, "|")
-}
-
-// cacheResults stores search results in cache
-func (sm *SearchManager) cacheResults(key string, results []viewer.SearchResult) {
-	sm.cacheMutex.Lock()
-	defer sm.cacheMutex.Unlock()
-
-	// Limit cache size
-	if len(sm.searchCache) > 100 {
-		// Remove oldest entries
-		for k := range sm.searchCache {
-			delete(sm.searchCache, k)
-			break
+	var searchedMessages atomic.Int64
+	return func() {
+		if searched := searchedMessages.Add(1); searched%progressInterval == 0 || searched == totalMessages {
+			reporter.SearchProgress(searched, totalMessages, "messages")
 		}
 	}
-
-	sm.searchCache[key] = results
 }
 
-// showProgress displays search progress
-func (sm *SearchManager) showProgress(ctx context.Context, progressChan <-chan float64, total int) {
-	startTime := time.Now()
-	lastUpdate := time.Now()
-
-	for {
-		select {
-		case <-ctx.Done():
-			// Clear progress line on cancellation
-			fmt.Printf("\r%s\r", strings.Repeat(" ", 80))
-			return
-		case progress, ok := <-progressChan:
-			if !ok {
-				// Channel closed, clear line and return
-				fmt.Printf("\r%s\r", strings.Repeat(" ", 80))
-				return
-			}
-
-			// Update every 100ms
-			if time.Since(lastUpdate) < 100*time.Millisecond {
-				continue
-			}
-
-			percentage := (progress / float64(total)) * 100
-			elapsed := time.Since(startTime)
-
-			// Clear line and show progress
-			fmt.Printf("\r")
-			color.New(color.FgYellow).Printf("Searching... %.1f%% (%d/%d messages) - %.1fs",
-				percentage, int(progress), total, elapsed.Seconds())
-
-			lastUpdate = time.Now()
+// streamProgress reports how far into stream the search has read. Progress
+// is measured in bytes since the message count is unknown upfront.
+func streamProgress(stream ConversationReader, reporter progress.Reporter) func() {
+	return func() {
+		if stream.Size() > 0 {
+			reporter.SearchProgress(stream.BytesRead(), stream.Size(), "bytes")
 		}
 	}
 }
 
 // ClearCache clears the search cache
 func (sm *SearchManager) ClearCache() {
-	sm.cacheMutex.Lock()
-	defer sm.cacheMutex.Unlock()
+	sm.cache.clear()
+}
+
+// SetCacheSize bounds the approximate memory, in bytes, held by cached
+// results, evicting the least recently used ones to fit. A size of 0
+// disables caching.
+func (sm *SearchManager) SetCacheSize(maxSize int64) error {
+	if maxSize < 0 {
+		return fmt.Errorf("invalid cache size: %d", maxSize)
+	}
 
-	sm.searchCache = make(map[string][]viewer.SearchResult)
+	sm.cache.mu.Lock()
+	defer sm.cache.mu.Unlock()
+
+	sm.cache.maxSize = maxSize
+	for sm.cache.size > sm.cache.maxSize {
+		sm.cache.remove(sm.cache.order.Back().Value.(*cacheEntry).Key)
+	}
+	return nil
 }
@@ -2,12 +2,16 @@ package search
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/beckxie/skype-history-viewer-cli/pkg/models"
+	"github.com/beckxie/skype-history-viewer-cli/pkg/utils"
+	"github.com/beckxie/skype-history-viewer-cli/pkg/viewer"
 )
 
 func TestSearchManager_Search_Cancellation(t *testing.T) {
@@ -157,15 +161,28 @@ func TestSearchManager_CacheLimit(t *testing.T) {
 	history := &models.SkypeHistoryRoot{}
 	sm := NewSearchManager(history)
 
+	results := []viewer.SearchResult{{MatchContext: strings.Repeat("x", 1000)}}
+	entrySize := int64(len("key-000")) + resultsSize(results)
+	if err := sm.SetCacheSize(10 * entrySize); err != nil {
+		t.Fatal(err)
+	}
+
 	for i := 0; i < 110; i++ {
-		sm.buildCacheKey(SearchOptions{Query: string(rune(i))})
-		sm.cacheResults(fmt.Sprintf("key-%d", i), nil)
+		sm.cache.put(fmt.Sprintf("key-%03d", i), results)
+	}
+
+	if n := sm.cache.len(); n != 10 {
+		t.Errorf("expected the cache to hold 10 entries, got %d", n)
+	}
+	if _, ok := sm.cache.get("key-109"); !ok {
+		t.Error("expected the most recent entry to be cached")
+	}
+	if _, ok := sm.cache.get("key-000"); ok {
+		t.Error("expected the oldest entry to be evicted")
 	}
 
-	sm.cacheMutex.RLock()
-	defer sm.cacheMutex.RUnlock()
-	if len(sm.searchCache) > 101 { // Actually it clears when > 100, so it might be small
-		// OK
+	if err := NewSearchManager(history).SetCacheSize(-1); err == nil {
+		t.Error("expected an error for a negative cache size")
 	}
 }
 
@@ -208,6 +225,53 @@ func TestSearchManager_SearchCacheHonorsLimit(t *testing.T) {
 	}
 }
 
+func TestSearchManager_SearchCacheTellsDateBoundsApart(t *testing.T) {
+	history := &models.SkypeHistoryRoot{
+		Conversations: []models.SkypeConversation{
+			{
+				MessageList: []models.SkypeMessage{
+					{Content: "apple early", MessageType: "Text", Timestamp: "2024-01-01T10:00:00Z"},
+					{Content: "apple late", MessageType: "Text", Timestamp: "2024-03-01T10:00:00Z"},
+				},
+			},
+		},
+	}
+	bound := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	sm := NewSearchManager(history)
+	after, err := sm.Search(context.Background(), SearchOptions{Query: "apple", SearchInContent: true, DateFrom: &bound})
+	if err != nil {
+		t.Fatal(err)
+	}
+	before, err := sm.Search(context.Background(), SearchOptions{Query: "apple", SearchInContent: true, DateTo: &bound})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(after) != 1 || after[0].Message.Content != "apple late" {
+		t.Errorf("unexpected results from the date: %+v", after)
+	}
+	if len(before) != 1 || before[0].Message.Content != "apple early" {
+		t.Errorf("unexpected results up to the date: %+v", before)
+	}
+}
+
+func TestSearchManager_BuildCacheKeyIsUnambiguous(t *testing.T) {
+	sm := NewSearchManager(nil)
+	pairs := [][2]SearchOptions{
+		{{SenderIds: []string{"8:a,8:b"}}, {SenderIds: []string{"8:a", "8:b"}}},
+		{{MessageTypes: []string{"Text,Event/Call"}}, {MessageTypes: []string{"Text", "Event/Call"}}},
+		{{Query: "apple|true"}, {Query: "apple", SearchInContent: true}},
+		{{Query: "apple|", ConversationFilter: "team"}, {Query: "apple", ConversationFilter: "|team"}},
+		{{OwnerId: "live:alice"}, {}},
+	}
+
+	for _, pair := range pairs {
+		if sm.buildCacheKey(pair[0]) == sm.buildCacheKey(pair[1]) {
+			t.Errorf("expected different keys for %+v and %+v", pair[0], pair[1])
+		}
+	}
+}
+
 func TestSearchManager_PreservesContextCasing(t *testing.T) {
 	history := &models.SkypeHistoryRoot{
 		Conversations: []models.SkypeConversation{
@@ -236,6 +300,282 @@ func TestSearchManager_PreservesContextCasing(t *testing.T) {
 	}
 }
 
+func TestSearchManager_RegexSearch(t *testing.T) {
+	history := &models.SkypeHistoryRoot{
+		Conversations: []models.SkypeConversation{
+			{
+				MessageList: []models.SkypeMessage{
+					{Content: "Ticket ABC-123 is done", From: "Alice", MessageType: "Text", Timestamp: "2024-01-01T10:00:00Z"},
+					{Content: "ticket abc-9 reopened", From: "Bob", MessageType: "Text", Timestamp: "2024-01-01T10:01:00Z"},
+					{Content: "No tickets here", From: "Bot-42", MessageType: "Text", Timestamp: "2024-01-01T10:02:00Z"},
+				},
+			},
+		},
+	}
+
+	sm := NewSearchManager(history)
+
+	t.Run("Case-insensitive content match", func(t *testing.T) {
+		results, err := sm.Search(context.Background(), SearchOptions{
+			Query:           `abc-\d+`,
+			RegexSearch:     true,
+			SearchInContent: true,
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(results) != 2 {
+			t.Fatalf("expected 2 results, got %d", len(results))
+		}
+		if !strings.Contains(results[0].MatchContext, "ABC-123") {
+			t.Errorf("expected context to highlight the regex match, got %q", results[0].MatchContext)
+		}
+	})
+
+	t.Run("Case-sensitive", func(t *testing.T) {
+		results, _ := sm.Search(context.Background(), SearchOptions{
+			Query:           `ABC-\d+`,
+			RegexSearch:     true,
+			CaseSensitive:   true,
+			SearchInContent: true,
+		})
+		if len(results) != 1 {
+			t.Errorf("expected 1 result, got %d", len(results))
+		}
+	})
+
+	t.Run("Sender match", func(t *testing.T) {
+		results, _ := sm.Search(context.Background(), SearchOptions{
+			Query:          `^bot-\d+$`,
+			RegexSearch:    true,
+			SearchInSender: true,
+		})
+		if len(results) != 1 || results[0].MatchType != "sender" {
+			t.Errorf("expected 1 sender match, got %+v", results)
+		}
+	})
+
+	t.Run("Empty matches are skipped", func(t *testing.T) {
+		// x* matches the empty string everywhere, but no message has an x
+		results, err := sm.Search(context.Background(), SearchOptions{
+			Query:           `x*`,
+			RegexSearch:     true,
+			SearchInContent: true,
+			SearchInSender:  true,
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(results) != 0 {
+			t.Errorf("expected no results, got %+v", results)
+		}
+
+		results, _ = sm.Search(context.Background(), SearchOptions{
+			Query:          `\d*42`,
+			RegexSearch:    true,
+			SearchInSender: true,
+		})
+		if len(results) != 1 || results[0].Message.From != "Bot-42" {
+			t.Errorf("expected only the real sender match, got %+v", results)
+		}
+	})
+
+	t.Run("Invalid expression", func(t *testing.T) {
+		_, err := sm.Search(context.Background(), SearchOptions{
+			Query:           "(unclosed",
+			RegexSearch:     true,
+			SearchInContent: true,
+		})
+		if err == nil {
+			t.Error("expected error for invalid regular expression")
+		}
+	})
+}
+
+func TestSearchManager_ExtractContext(t *testing.T) {
+	sm := NewSearchManager(nil)
+	text := strings.Repeat("a", 60) + "MATCH" + strings.Repeat("b", 60)
+
+	got := sm.extractContext(text, []span{{60, 65}}, 10)
+	if !strings.HasPrefix(got, "...") || !strings.HasSuffix(got, "...") {
+		t.Errorf("expected ellipsis on both sides, got %q", got)
+	}
+	if !strings.Contains(got, "MATCH") {
+		t.Errorf("expected match in context, got %q", got)
+	}
+}
+
 func stringPtr(s string) *string {
 	return &s
 }
+
+func TestSearchManager_SearchStream(t *testing.T) {
+	jsonContent := `{"userId": "u", "conversations": [
+		{"id": "a", "displayName": "First", "MessageList": [
+			{"id": "m1", "content": "apple pie", "messagetype": "Text", "originalarrivaltime": "2024-01-01T10:00:00Z"},
+			{"id": "m2", "content": "banana", "messagetype": "Text", "originalarrivaltime": "2024-01-01T10:01:00Z"}
+		]},
+		{"id": "b", "displayName": "Second", "MessageList": [
+			{"id": "m3", "content": "apple juice", "messagetype": "Text", "originalarrivaltime": "2024-01-01T10:02:00Z"}
+		]}
+	]}`
+
+	sm := NewSearchManager(nil)
+	stream := utils.NewHistoryStream(strings.NewReader(jsonContent))
+	results, err := sm.SearchStream(context.Background(), stream, SearchOptions{
+		Query:           "apple",
+		SearchInContent: true,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+	if results[0].ConversationName != "First" || results[1].ConversationName != "Second" {
+		t.Errorf("unexpected conversation names: %q, %q", results[0].ConversationName, results[1].ConversationName)
+	}
+}
+
+func TestSearchManager_SurroundingMessages(t *testing.T) {
+	history := &models.SkypeHistoryRoot{
+		Conversations: []models.SkypeConversation{
+			{
+				DisplayName: stringPtr("Team"),
+				MessageList: []models.SkypeMessage{
+					{OriginalId: "m1", Content: "good morning", MessageType: "Text"},
+					{OriginalId: "s1", Content: "joined", MessageType: "ThreadActivity/AddMember"},
+					{OriginalId: "m2", Content: "deploy failed", MessageType: "Text"},
+					{OriginalId: "m3", Content: "looking into it", MessageType: "Text"},
+				},
+			},
+		},
+	}
+	sm := NewSearchManager(history)
+
+	results, err := sm.Search(context.Background(), SearchOptions{
+		Query:           "deploy",
+		SearchInContent: true,
+		ContextBefore:   2,
+		ContextAfter:    2,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("expected 1 result, got %d", len(results))
+	}
+	if len(results[0].Before) != 1 || results[0].Before[0].OriginalId != "m1" {
+		t.Errorf("expected m1 before the hit, got %+v", results[0].Before)
+	}
+	if len(results[0].After) != 1 || results[0].After[0].OriginalId != "m3" {
+		t.Errorf("expected m3 after the hit, got %+v", results[0].After)
+	}
+
+	// Without context options no surrounding messages are attached
+	results, _ = sm.Search(context.Background(), SearchOptions{Query: "deploy", SearchInContent: true})
+	if len(results) != 1 || results[0].Before != nil || results[0].After != nil {
+		t.Errorf("expected no surrounding messages, got %+v", results)
+	}
+}
+
+func TestSearchManager_NormalizedSearch(t *testing.T) {
+	history := &models.SkypeHistoryRoot{
+		Conversations: []models.SkypeConversation{
+			{
+				DisplayName: stringPtr("週會"),
+				MessageList: []models.SkypeMessage{
+					{Content: "明天在會議室開會", From: "alice", MessageType: "Text", Timestamp: "2024-01-01T10:00:00Z"},
+					{Content: "Team meeting at 3", From: "bob", MessageType: "Text", Timestamp: "2024-01-01T10:01:00Z"},
+					{Content: "ＭＥＥＴＩＮＧ　ｍｏｖｅｄ", From: "carol", MessageType: "Text", Timestamp: "2024-01-01T10:02:00Z"},
+					{Content: "ﾐｰﾃｨﾝｸﾞ", From: "dave", MessageType: "Text", Timestamp: "2024-01-01T10:03:00Z"},
+				},
+			},
+		},
+	}
+	sm := NewSearchManager(history)
+
+	tests := []struct {
+		query         string
+		caseSensitive bool
+		want          int
+	}{
+		{"會議", false, 1},
+		{"ｍｅｅｔｉｎｇ", false, 2},
+		{"meeting", false, 2},
+		{"MEETING", true, 1},
+		{"ミーティング", false, 1},
+	}
+
+	for _, tt := range tests {
+		results, err := sm.Search(context.Background(), SearchOptions{
+			Query:           tt.query,
+			CaseSensitive:   tt.caseSensitive,
+			SearchInContent: true,
+		})
+		if err != nil {
+			t.Fatalf("%q: unexpected error: %v", tt.query, err)
+		}
+		if len(results) != tt.want {
+			t.Errorf("%q: expected %d results, got %d", tt.query, tt.want, len(results))
+		}
+	}
+
+	// The context highlights the original full-width text
+	results, _ := sm.Search(context.Background(), SearchOptions{Query: "moved", SearchInContent: true})
+	if len(results) != 1 || !strings.Contains(results[0].MatchContext, "ｍｏｖｅｄ") {
+		t.Errorf("expected context to contain the original text, got %+v", results)
+	}
+
+	results, _ = sm.Search(context.Background(), SearchOptions{Query: "會議", SearchInContent: true, ConversationFilter: "週會"})
+	if len(results) != 1 {
+		t.Errorf("expected conversation filter to match, got %d results", len(results))
+	}
+}
+
+func TestSearchManager_SortDateAppliesLimitAfterSorting(t *testing.T) {
+	// The newest conversation comes first in the export
+	jsonContent := `{"userId": "u", "conversations": [
+		{"id": "a", "MessageList": [
+			{"id": "m1", "content": "hello", "messagetype": "Text", "originalarrivaltime": "2024-05-01T10:00:00Z"},
+			{"id": "m2", "content": "hello again", "messagetype": "Text", "originalarrivaltime": "2024-05-02T10:00:00Z"}
+		]},
+		{"id": "b", "MessageList": [
+			{"id": "m4", "content": "hello there", "messagetype": "Text", "originalarrivaltime": "2022-01-01T10:00:00Z"},
+			{"id": "m3", "content": "hello", "messagetype": "Text", "originalarrivaltime": "2020-01-01T10:00:00Z"}
+		]}
+	]}`
+	options := SearchOptions{Query: "hello", SearchInContent: true, Sort: SortDate, Limit: 1}
+
+	history := &models.SkypeHistoryRoot{}
+	if err := json.Unmarshal([]byte(jsonContent), history); err != nil {
+		t.Fatal(err)
+	}
+	results, err := NewSearchManager(history).Search(context.Background(), options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := resultIds(results); !reflect.DeepEqual(ids, []string{"m3"}) {
+		t.Errorf("Search() = %v, want [m3]", ids)
+	}
+
+	stream := utils.NewHistoryStream(strings.NewReader(jsonContent))
+	results, err = NewSearchManager(nil).SearchStream(context.Background(), stream, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := resultIds(results); !reflect.DeepEqual(ids, []string{"m3"}) {
+		t.Errorf("SearchStream() = %v, want [m3]", ids)
+	}
+
+	var ids []string
+	for result, err := range NewSearchManager(history).Results(context.Background(), options) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, result.Message.OriginalId)
+	}
+	if !reflect.DeepEqual(ids, []string{"m3"}) {
+		t.Errorf("Results() = %v, want [m3]", ids)
+	}
+}
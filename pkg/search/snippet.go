@@ -0,0 +1,130 @@
+package search
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/fatih/color"
+)
+
+// DefaultContextWidth is how many characters of context are shown on each
+// side of a match when SearchOptions.ContextWidth is not set
+const DefaultContextWidth = 50
+
+// span is a byte range [start, end) of a text
+type span struct {
+	start, end int
+}
+
+// buildSnippet renders text around every match, highlighting each one.
+// Windows of width characters (grapheme clusters) on each side of a match
+// are merged when they overlap, and gaps between them are elided.
+func buildSnippet(text string, matches []span, width int) string {
+	if len(matches) == 0 {
+		return ""
+	}
+	matches = mergeSpans(matches)
+	bounds := graphemeBoundaries(text)
+
+	// Clamp each match to whole clusters and widen it into a window
+	type window struct {
+		first, last int // cluster indexes, last exclusive
+		matches     []span
+	}
+	var windows []window
+	for _, m := range matches {
+		first := sort.SearchInts(bounds, m.start+1) - 1
+		last := sort.SearchInts(bounds, m.end)
+		m = span{bounds[first], bounds[last]}
+
+		w := window{first: max(first-width, 0), last: min(last+width, len(bounds)-1), matches: []span{m}}
+		if n := len(windows); n > 0 && w.first <= windows[n-1].last {
+			windows[n-1].last = max(windows[n-1].last, w.last)
+			windows[n-1].matches = append(windows[n-1].matches, m)
+			continue
+		}
+		windows = append(windows, w)
+	}
+
+	highlight := color.New(color.FgYellow, color.Bold)
+	var b strings.Builder
+	if windows[0].first > 0 {
+		b.WriteString("...")
+	}
+	for i, w := range windows {
+		if i > 0 {
+			b.WriteString(" ... ")
+		}
+		pos := bounds[w.first]
+		for _, m := range mergeSpans(w.matches) {
+			b.WriteString(text[pos:m.start])
+			b.WriteString(highlight.Sprint(text[m.start:m.end]))
+			pos = m.end
+		}
+		b.WriteString(text[pos:bounds[w.last]])
+	}
+	if windows[len(windows)-1].last < len(bounds)-1 {
+		b.WriteString("...")
+	}
+
+	return b.String()
+}
+
+// mergeSpans sorts spans and joins those that overlap or touch
+func mergeSpans(spans []span) []span {
+	sorted := make([]span, len(spans))
+	copy(sorted, spans)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].start < sorted[j].start })
+
+	merged := sorted[:0]
+	for _, s := range sorted {
+		if n := len(merged); n > 0 && s.start <= merged[n-1].end {
+			merged[n-1].end = max(merged[n-1].end, s.end)
+			continue
+		}
+		merged = append(merged, s)
+	}
+	return merged
+}
+
+// graphemeBoundaries returns the byte offsets at which user-perceived
+// characters start, followed by len(text). Combining marks, variation
+// selectors, emoji modifiers and tags stay with their base character,
+// zero-width joiners glue emoji sequences together and regional indicators
+// pair up into flags.
+func graphemeBoundaries(text string) []int {
+	bounds := make([]int, 0, len(text)+1)
+	joinNext := false
+	regionalRun := 0
+
+	for i, r := range text {
+		extend := joinNext || isGraphemeExtender(r)
+		if isRegionalIndicator(r) {
+			// Flags are pairs of regional indicators
+			extend = extend || regionalRun%2 == 1
+			regionalRun++
+		} else {
+			regionalRun = 0
+		}
+		if !extend || len(bounds) == 0 {
+			bounds = append(bounds, i)
+		}
+		joinNext = r == '‍'
+	}
+
+	return append(bounds, len(text))
+}
+
+// isGraphemeExtender reports whether r attaches to the preceding character
+func isGraphemeExtender(r rune) bool {
+	return unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc) ||
+		r == '‍' ||
+		(r >= 0xFE00 && r <= 0xFE0F) || // variation selectors
+		(r >= 0x1F3FB && r <= 0x1F3FF) || // skin tone modifiers
+		(r >= 0xE0020 && r <= 0xE007F) // tags
+}
+
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1F1E6 && r <= 0x1F1FF
+}
@@ -0,0 +1,108 @@
+package search
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/fatih/color"
+)
+
+func TestGraphemeBoundaries(t *testing.T) {
+	tests := []struct {
+		text string
+		want []int
+	}{
+		{"abc", []int{0, 1, 2, 3}},
+		{"會議", []int{0, 3, 6}},
+		{"éx", []int{0, 3, 4}},      // combining accent
+		{"👍🏽!", []int{0, 8, 9}},      // skin tone modifier
+		{"👨‍👩‍👧a", []int{0, 18, 19}}, // ZWJ family
+		{"🇹🇼🇯🇵", []int{0, 8, 16}},    // two flags
+		{"❤️x", []int{0, 6, 7}},      // variation selector
+		{"", []int{0}},
+	}
+
+	for _, tt := range tests {
+		if got := graphemeBoundaries(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("graphemeBoundaries(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestBuildSnippet(t *testing.T) {
+	noColor := color.NoColor
+	color.NoColor = false
+	defer func() { color.NoColor = noColor }()
+	highlighted := func(s string) string {
+		return color.New(color.FgYellow, color.Bold).Sprint(s)
+	}
+
+	t.Run("keeps runes whole", func(t *testing.T) {
+		text := strings.Repeat("會議", 10) + "開會" + strings.Repeat("😀", 10)
+		start := strings.Index(text, "開會")
+		got := buildSnippet(text, []span{{start, start + len("開會")}}, 3)
+
+		if !utf8.ValidString(got) {
+			t.Fatalf("snippet is not valid UTF-8: %q", got)
+		}
+		want := "...議會議" + highlighted("開會") + "😀😀😀..."
+		if got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	})
+
+	t.Run("highlights every occurrence", func(t *testing.T) {
+		got := buildSnippet("cat and cat", []span{{0, 3}, {8, 11}}, 10)
+		want := highlighted("cat") + " and " + highlighted("cat")
+		if got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	})
+
+	t.Run("separates distant windows", func(t *testing.T) {
+		text := "x" + strings.Repeat(".", 30) + "y" + strings.Repeat(".", 30) + "x"
+		got := buildSnippet(text, []span{{0, 1}, {62, 63}}, 2)
+		want := highlighted("x") + ".." + " ... " + ".." + highlighted("x")
+		if got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	})
+
+	t.Run("merges overlapping matches", func(t *testing.T) {
+		got := buildSnippet("abcdef", []span{{1, 4}, {2, 5}}, 0)
+		want := "..." + highlighted("bcde") + "..."
+		if got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	})
+
+	t.Run("widens matches inside a cluster", func(t *testing.T) {
+		text := "a👍🏽b"
+		// Span covering only the thumbs up, without its modifier
+		got := buildSnippet(text, []span{{1, 5}}, 0)
+		want := "..." + highlighted("👍🏽") + "..."
+		if got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	})
+}
+
+func TestSearchManager_ContextWidthAndMultipleMatches(t *testing.T) {
+	text := "apple " + strings.Repeat("x", 40) + " apple"
+	sm := NewSearchManager(nil)
+	m, err := newMatcher(SearchOptions{Query: "APPLE", SearchInContent: true, ContextWidth: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	matches := m.findAll(text)
+	if len(matches) != 2 {
+		t.Fatalf("expected 2 matches, got %v", matches)
+	}
+	got := sm.extractContext(text, matches, 5)
+	if got != "apple xxxx ... xxxx apple" {
+		t.Errorf("unexpected context %q", got)
+	}
+}
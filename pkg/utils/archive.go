@@ -0,0 +1,366 @@
+package utils
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// messagesFileName is the name of the chat history inside an export
+const messagesFileName = "messages.json"
+
+// mediaDirName is the folder holding attachments inside an export
+const mediaDirName = "media"
+
+// exportSource is an opened messages.json stream read from a plain file,
+// an extracted export directory or an export .tar archive
+type exportSource struct {
+	io.Reader
+	closer     io.Closer
+	name       string
+	size       int64
+	file       string // file holding messages.json
+	dataOffset int64  // start of messages.json within file
+}
+
+// Close releases the underlying file
+func (s *exportSource) Close() error {
+	return s.closer.Close()
+}
+
+// MediaEntry describes a file stored under media/ in an export
+type MediaEntry struct {
+	Name string
+	Size int64
+}
+
+// IsTarArchive reports whether path is a tar archive such as the
+// 8_live_<user>_export.tar file handed out by Microsoft
+func IsTarArchive(path string) bool {
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	// The ustar magic lives at offset 257 of the first header block
+	header := make([]byte, 263)
+	if _, err := io.ReadFull(file, header); err != nil {
+		return false
+	}
+	return bytes.HasPrefix(header[257:], []byte("ustar"))
+}
+
+// ResolveExportFile returns the file that holds messages.json for an export
+// path: the archive itself, the JSON file, or messages.json in a directory
+func ResolveExportFile(exportPath string) (string, error) {
+	info, err := os.Stat(exportPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to access path: %w", err)
+	}
+	if !info.IsDir() {
+		return exportPath, nil
+	}
+
+	jsonPath := filepath.Join(exportPath, messagesFileName)
+	if _, err := os.Stat(jsonPath); os.IsNotExist(err) {
+		return "", fmt.Errorf("messages.json not found in directory: %s", exportPath)
+	}
+	return jsonPath, nil
+}
+
+// ExpandExports replaces every directory holding several exports, rather
+// than being an export itself, with the exports found in it
+func ExpandExports(paths []string) ([]string, error) {
+	var exports []string
+	for _, exportPath := range paths {
+		info, err := os.Stat(exportPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to access path: %w", err)
+		}
+		if !info.IsDir() || isExportDir(exportPath) {
+			exports = append(exports, exportPath)
+			continue
+		}
+
+		found, err := FindExports(exportPath)
+		if err != nil {
+			return nil, err
+		}
+		exports = append(exports, found...)
+	}
+	return exports, nil
+}
+
+// FindExports lists the exports directly inside dir, in name order: JSON
+// files, export archives and export directories
+func FindExports(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory: %w", err)
+	}
+
+	var exports []string
+	for _, entry := range entries {
+		exportPath := filepath.Join(dir, entry.Name())
+		switch {
+		case entry.IsDir():
+			if isExportDir(exportPath) {
+				exports = append(exports, exportPath)
+			}
+		case strings.EqualFold(filepath.Ext(entry.Name()), ".json"), IsTarArchive(exportPath):
+			exports = append(exports, exportPath)
+		}
+	}
+
+	if len(exports) == 0 {
+		return nil, fmt.Errorf("no exports found in directory: %s", dir)
+	}
+	return exports, nil
+}
+
+// isExportDir reports whether dir is an extracted export
+func isExportDir(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, messagesFileName))
+	return err == nil
+}
+
+// openExportSource opens the messages.json stream for the given path
+func openExportSource(exportPath string) (*exportSource, error) {
+	info, err := os.Stat(exportPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to access path: %w", err)
+	}
+
+	if !info.IsDir() && IsTarArchive(exportPath) {
+		return openTarExportSource(exportPath)
+	}
+
+	jsonPath, err := ResolveExportFile(exportPath)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(jsonPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+
+	fileInfo, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to get file info: %w", err)
+	}
+
+	return &exportSource{
+		Reader: file,
+		closer: file,
+		name:   jsonPath,
+		size:   fileInfo.Size(),
+		file:   jsonPath,
+	}, nil
+}
+
+// openTarExportSource streams messages.json out of an export archive
+// without extracting it
+func openTarExportSource(archivePath string) (*exportSource, error) {
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive: %w", err)
+	}
+
+	reader := tar.NewReader(file)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			file.Close()
+			return nil, fmt.Errorf("messages.json not found in archive: %s", archivePath)
+		}
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to read archive: %w", err)
+		}
+
+		if header.Typeflag == tar.TypeReg && archiveEntryName(header.Name) == messagesFileName {
+			// tar.Reader reads the file unbuffered, so the current position
+			// is where the entry data starts
+			dataOffset, err := file.Seek(0, io.SeekCurrent)
+			if err != nil {
+				file.Close()
+				return nil, fmt.Errorf("failed to read archive: %w", err)
+			}
+
+			return &exportSource{
+				Reader:     reader,
+				closer:     file,
+				name:       archivePath + ":" + header.Name,
+				size:       header.Size,
+				file:       archivePath,
+				dataOffset: dataOffset,
+			}, nil
+		}
+	}
+}
+
+// archiveEntryName returns the entry path relative to the export root,
+// tolerating archives that wrap everything in a single top-level folder
+func archiveEntryName(name string) string {
+	name = strings.TrimPrefix(path.Clean(strings.TrimPrefix(name, "./")), "/")
+	if name == messagesFileName || strings.HasPrefix(name, mediaDirName+"/") {
+		return name
+	}
+
+	if i := strings.Index(name, "/"); i >= 0 {
+		rest := name[i+1:]
+		if rest == messagesFileName || strings.HasPrefix(rest, mediaDirName+"/") {
+			return rest
+		}
+	}
+
+	return name
+}
+
+// ListMedia returns the files under media/ of an export directory or archive
+func ListMedia(exportPath string) ([]MediaEntry, error) {
+	info, err := os.Stat(exportPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to access path: %w", err)
+	}
+
+	var entries []MediaEntry
+	switch {
+	case info.IsDir():
+		dirEntries, err := os.ReadDir(filepath.Join(exportPath, mediaDirName))
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read media directory: %w", err)
+		}
+		for _, entry := range dirEntries {
+			if !entry.Type().IsRegular() {
+				continue
+			}
+			entryInfo, err := entry.Info()
+			if err != nil {
+				return nil, fmt.Errorf("failed to stat media file: %w", err)
+			}
+			entries = append(entries, MediaEntry{Name: entry.Name(), Size: entryInfo.Size()})
+		}
+	case IsTarArchive(exportPath):
+		file, err := os.Open(exportPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open archive: %w", err)
+		}
+		defer file.Close()
+
+		reader := tar.NewReader(file)
+		for {
+			header, err := reader.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to read archive: %w", err)
+			}
+			if header.Typeflag != tar.TypeReg {
+				continue
+			}
+			if name, ok := mediaEntryName(header.Name); ok {
+				entries = append(entries, MediaEntry{Name: name, Size: header.Size})
+			}
+		}
+	default:
+		// A bare messages.json has no media folder attached
+		return nil, nil
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name < entries[j].Name
+	})
+	return entries, nil
+}
+
+// OpenMedia opens a single file from the media/ folder of an export
+func OpenMedia(exportPath, name string) (io.ReadCloser, error) {
+	if name == "" || strings.Contains(name, "/") || strings.Contains(name, `\`) {
+		return nil, fmt.Errorf("invalid media name: %q", name)
+	}
+
+	info, err := os.Stat(exportPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to access path: %w", err)
+	}
+
+	if info.IsDir() {
+		// Lstat so that symlinks are rejected rather than followed
+		mediaPath := filepath.Join(exportPath, mediaDirName, name)
+		entryInfo, err := os.Lstat(mediaPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open media file: %w", err)
+		}
+		if !entryInfo.Mode().IsRegular() {
+			return nil, fmt.Errorf("media entry is not a regular file: %s", name)
+		}
+
+		file, err := os.Open(mediaPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open media file: %w", err)
+		}
+		// The entry could have been swapped for a link since Lstat
+		fileInfo, err := file.Stat()
+		if err != nil || !os.SameFile(entryInfo, fileInfo) {
+			file.Close()
+			return nil, fmt.Errorf("media entry is not a regular file: %s", name)
+		}
+		return file, nil
+	}
+
+	if !IsTarArchive(exportPath) {
+		return nil, fmt.Errorf("media files are only available for export directories and archives")
+	}
+
+	file, err := os.Open(exportPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive: %w", err)
+	}
+
+	reader := tar.NewReader(file)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			file.Close()
+			return nil, fmt.Errorf("media file not found in archive: %s", name)
+		}
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to read archive: %w", err)
+		}
+		if entryName, ok := mediaEntryName(header.Name); ok && entryName == name {
+			if header.Typeflag != tar.TypeReg {
+				file.Close()
+				return nil, fmt.Errorf("media entry is not a regular file: %s", name)
+			}
+			return &exportSource{Reader: reader, closer: file, name: header.Name, size: header.Size}, nil
+		}
+	}
+}
+
+// mediaEntryName returns the file name of an archive entry under media/
+func mediaEntryName(entry string) (string, bool) {
+	name := archiveEntryName(entry)
+	if !strings.HasPrefix(name, mediaDirName+"/") {
+		return "", false
+	}
+	name = strings.TrimPrefix(name, mediaDirName+"/")
+	if name == "" || strings.Contains(name, "/") {
+		return "", false
+	}
+	return name, true
+}
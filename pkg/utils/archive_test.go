@@ -0,0 +1,215 @@
+package utils
+
+import (
+	"archive/tar"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeTestArchive(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	archivePath := filepath.Join(t.TempDir(), "8_live_test_export.tar")
+	file, err := os.Create(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	tw := tar.NewWriter(file)
+	for name, content := range files {
+		header := &tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return archivePath
+}
+
+func TestLoadSkypeHistoryFromArchive(t *testing.T) {
+	archivePath := writeTestArchive(t, map[string]string{
+		"endpoints.json":  `{"endpoints": []}`,
+		"media/photo.jpg": "jpeg-bytes",
+		"messages.json": `{
+			"userId": "archive-user",
+			"exportDate": "2024-01-01T00:00:00Z",
+			"conversations": [{"id": "conv1", "MessageList": [{"id": "m1", "content": "Hi"}]}]
+		}`,
+	})
+
+	if !IsTarArchive(archivePath) {
+		t.Fatal("expected archive to be detected as tar")
+	}
+
+	history, err := LoadSkypeHistory(archivePath, LoadOptions{})
+	if err != nil {
+		t.Fatalf("LoadSkypeHistory(archive) error = %v", err)
+	}
+	if history.UserId != "archive-user" {
+		t.Errorf("expected userId archive-user, got %s", history.UserId)
+	}
+	if len(history.Conversations) != 1 {
+		t.Errorf("expected 1 conversation, got %d", len(history.Conversations))
+	}
+}
+
+func TestLoadSkypeHistoryFromArchiveWithoutMessages(t *testing.T) {
+	archivePath := writeTestArchive(t, map[string]string{
+		"endpoints.json": `{"endpoints": []}`,
+	})
+
+	if _, err := LoadSkypeHistory(archivePath, LoadOptions{}); err == nil {
+		t.Error("expected error for archive without messages.json")
+	}
+}
+
+func TestListMedia(t *testing.T) {
+	archivePath := writeTestArchive(t, map[string]string{
+		"export/messages.json": `{"conversations": []}`,
+		"export/media/b.png":   "png",
+		"export/media/a.jpeg":  "jpeg!",
+	})
+
+	entries, err := ListMedia(archivePath)
+	if err != nil {
+		t.Fatalf("ListMedia(archive) error = %v", err)
+	}
+	if len(entries) != 2 || entries[0].Name != "a.jpeg" || entries[0].Size != 5 || entries[1].Name != "b.png" {
+		t.Errorf("unexpected archive media entries: %+v", entries)
+	}
+
+	entries, err = ListMedia("../../testdata/8_live_generic_user_1_export")
+	if err != nil {
+		t.Fatalf("ListMedia(dir) error = %v", err)
+	}
+	if len(entries) != 3 {
+		t.Errorf("expected 3 media entries in directory, got %d", len(entries))
+	}
+}
+
+func TestOpenMedia(t *testing.T) {
+	archivePath := writeTestArchive(t, map[string]string{
+		"messages.json":   `{"conversations": []}`,
+		"media/photo.jpg": "jpeg-bytes",
+	})
+
+	rc, err := OpenMedia(archivePath, "photo.jpg")
+	if err != nil {
+		t.Fatalf("OpenMedia error = %v", err)
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "jpeg-bytes" {
+		t.Errorf("unexpected media content: %q", data)
+	}
+
+	if _, err := OpenMedia(archivePath, "missing.jpg"); err == nil {
+		t.Error("expected error for missing media file")
+	}
+	if _, err := OpenMedia(archivePath, "../messages.json"); err == nil {
+		t.Error("expected error for path traversal")
+	}
+}
+
+func TestOpenMediaRejectsNonRegularFiles(t *testing.T) {
+	archivePath := filepath.Join(t.TempDir(), "8_live_test_export.tar")
+	file, err := os.Create(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tw := tar.NewWriter(file)
+	if err := tw.WriteHeader(&tar.Header{Name: "messages.json", Mode: 0644, Typeflag: tar.TypeReg}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.WriteHeader(&tar.Header{Name: "media/link.jpg", Linkname: "/etc/passwd", Typeflag: tar.TypeSymlink}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	if _, err := OpenMedia(archivePath, "link.jpg"); err == nil {
+		t.Error("expected error for a symlink entry in an archive")
+	}
+
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "media", "album"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenMedia(dir, "album"); err == nil {
+		t.Error("expected error for a directory under media/")
+	}
+	target := filepath.Join(t.TempDir(), "secret.txt")
+	if err := os.WriteFile(target, []byte("secret"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(target, filepath.Join(dir, "media", "link.jpg")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenMedia(dir, "link.jpg"); err == nil {
+		t.Error("expected error for a symlink under media/")
+	}
+	entries, err := ListMedia(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("expected no media files, got %+v", entries)
+	}
+}
+
+func TestExpandExports(t *testing.T) {
+	dir := t.TempDir()
+	exportsDir := filepath.Join(dir, "team")
+	for _, name := range []string{"alice", "bob", "notes"} {
+		if err := os.MkdirAll(filepath.Join(exportsDir, name), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, path := range []string{
+		filepath.Join(exportsDir, "alice", "messages.json"),
+		filepath.Join(exportsDir, "bob", "messages.json"),
+		filepath.Join(exportsDir, "carol.json"),
+	} {
+		if err := os.WriteFile(path, []byte(`{"conversations": []}`), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(exportsDir, "readme.txt"), []byte("not an export"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	single := filepath.Join(exportsDir, "alice")
+	exports, err := ExpandExports([]string{single, exportsDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		single,
+		filepath.Join(exportsDir, "alice"),
+		filepath.Join(exportsDir, "bob"),
+		filepath.Join(exportsDir, "carol.json"),
+	}
+	if !reflect.DeepEqual(exports, want) {
+		t.Errorf("expected %v, got %v", want, exports)
+	}
+
+	if _, err := ExpandExports([]string{filepath.Join(exportsDir, "notes")}); err == nil {
+		t.Error("expected an error for a directory without exports")
+	}
+}
@@ -0,0 +1,22 @@
+package utils
+
+import "io"
+
+// countingReader counts the bytes read through it, calling onRead with the
+// running total after every read
+type countingReader struct {
+	r      io.Reader
+	n      int64
+	onRead func(bytesRead int64)
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	if n > 0 {
+		c.n += int64(n)
+		if c.onRead != nil {
+			c.onRead(c.n)
+		}
+	}
+	return n, err
+}
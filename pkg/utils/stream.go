@@ -0,0 +1,442 @@
+package utils
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/beckxie/skype-history-viewer-cli/pkg/models"
+	"github.com/beckxie/skype-history-viewer-cli/pkg/progress"
+)
+
+type streamState int
+
+const (
+	streamStart streamState = iota
+	streamInConversations
+	streamDone
+)
+
+// HistoryStream reads a Skype export one conversation at a time, walking
+// the root object with json.Decoder.Token so that only the conversation
+// currently being decoded is held in memory
+type HistoryStream struct {
+	// UserId and ExportDate are filled in as they are encountered. Exports
+	// list them before the conversations, but for files that don't they are
+	// only final once Next has returned io.EOF.
+	UserId     string
+	ExportDate string
+
+	decoder  *json.Decoder
+	source   *exportSource
+	size     int64
+	state    streamState
+	offsets  ConversationOffsets
+	reporter progress.Reporter
+
+	conversations int
+	messages      int
+}
+
+// ConversationOffsets locates a conversation and its messages within
+// messages.json. Offsets point just past the previous token, so they may
+// be followed by whitespace and a comma before the value itself.
+type ConversationOffsets struct {
+	Conversation int64
+	Messages     []int64
+}
+
+// NewHistoryStream creates a stream reading messages.json content from r
+func NewHistoryStream(r io.Reader) *HistoryStream {
+	return &HistoryStream{decoder: json.NewDecoder(r), reporter: progress.Silent}
+}
+
+// OpenHistoryStream opens a JSON file, export directory or export archive
+// for streaming. Progress is reported as the file is read.
+func OpenHistoryStream(path string, options LoadOptions) (*HistoryStream, error) {
+	source, err := openExportSource(path)
+	if err != nil {
+		return nil, err
+	}
+
+	reporter := progress.Or(options.Reporter)
+	reporter.LoadStarted(source.name, source.size)
+
+	stream := newReportingStream(source, reporter)
+	stream.source = source
+	return stream, nil
+}
+
+// newReportingStream creates a stream reading source that reports how many
+// of its bytes have been consumed as it goes
+func newReportingStream(source *exportSource, reporter progress.Reporter) *HistoryStream {
+	counter := &countingReader{r: source}
+	stream := NewHistoryStream(counter)
+	stream.size = source.size
+	stream.reporter = reporter
+	counter.onRead = func(bytesRead int64) {
+		reporter.LoadProgress(bytesRead, source.size, stream.conversations)
+	}
+	return stream
+}
+
+// Next returns the next conversation, or io.EOF once all have been read
+func (s *HistoryStream) Next() (*models.SkypeConversation, error) {
+	if s.state == streamStart {
+		if err := s.readHeader(); err != nil {
+			return nil, err
+		}
+	}
+
+	if s.state == streamDone {
+		return nil, io.EOF
+	}
+
+	if s.decoder.More() {
+		conv, err := s.decodeConversation()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse conversation: %w", err)
+		}
+		s.conversations++
+		s.messages += len(conv.MessageList)
+		return conv, nil
+	}
+
+	// Consume the closing bracket of the conversations array
+	if err := s.expectDelim(']'); err != nil {
+		return nil, err
+	}
+	if err := s.readFields(); err != nil {
+		return nil, err
+	}
+
+	s.state = streamDone
+	s.reporter.LoadFinished(progress.LoadSummary{
+		UserId:        s.UserId,
+		ExportDate:    s.ExportDate,
+		Conversations: s.conversations,
+		Messages:      s.messages,
+	})
+	return nil, io.EOF
+}
+
+// BytesRead returns how far into messages.json the stream has read
+func (s *HistoryStream) BytesRead() int64 {
+	return s.decoder.InputOffset()
+}
+
+// Size returns the size of messages.json, or 0 when unknown
+func (s *HistoryStream) Size() int64 {
+	return s.size
+}
+
+// Offsets returns where the conversation last returned by Next and its
+// messages are located within messages.json
+func (s *HistoryStream) Offsets() ConversationOffsets {
+	return s.offsets
+}
+
+// SourceFile returns the file holding messages.json, or "" for streams
+// not opened from a path
+func (s *HistoryStream) SourceFile() string {
+	if s.source == nil {
+		return ""
+	}
+	return s.source.file
+}
+
+// DataOffset returns where messages.json starts within SourceFile
+func (s *HistoryStream) DataOffset() int64 {
+	if s.source == nil {
+		return 0
+	}
+	return s.source.dataOffset
+}
+
+// Close releases the underlying file
+func (s *HistoryStream) Close() error {
+	if s.source == nil {
+		return nil
+	}
+	return s.source.Close()
+}
+
+// decodeConversation walks a single conversation object, decoding its
+// messages one by one so their offsets can be recorded
+func (s *HistoryStream) decodeConversation() (*models.SkypeConversation, error) {
+	s.offsets = ConversationOffsets{Conversation: s.decoder.InputOffset()}
+	if err := s.expectDelim('{'); err != nil {
+		return nil, err
+	}
+
+	conv := &models.SkypeConversation{}
+	for s.decoder.More() {
+		token, err := s.decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, ok := token.(string)
+		if !ok {
+			return nil, fmt.Errorf("unexpected token %v", token)
+		}
+
+		// encoding/json matches field names case-insensitively, so do the same
+		switch strings.ToLower(key) {
+		case "id":
+			err = s.decoder.Decode(&conv.Id)
+		case "displayname":
+			err = s.decoder.Decode(&conv.DisplayName)
+		case "version":
+			err = s.decoder.Decode(&conv.Version)
+		case "properties":
+			err = s.decoder.Decode(&conv.Properties)
+		case "threadproperties":
+			err = s.decoder.Decode(&conv.ThreadProperties)
+		case "messagelist":
+			err = s.decodeMessages(conv)
+		default:
+			err = s.skipValue()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("field %q: %w", key, err)
+		}
+	}
+
+	if err := s.expectDelim('}'); err != nil {
+		return nil, err
+	}
+	return conv, nil
+}
+
+// decodeMessages decodes the MessageList array of a conversation
+func (s *HistoryStream) decodeMessages(conv *models.SkypeConversation) error {
+	token, err := s.decoder.Token()
+	if err != nil {
+		return err
+	}
+	if token == nil {
+		return nil
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '[' {
+		return fmt.Errorf("MessageList is not an array")
+	}
+
+	for s.decoder.More() {
+		offset := s.decoder.InputOffset()
+		var msg models.SkypeMessage
+		if err := s.decoder.Decode(&msg); err != nil {
+			return err
+		}
+		conv.MessageList = append(conv.MessageList, msg)
+		s.offsets.Messages = append(s.offsets.Messages, offset)
+	}
+
+	return s.expectDelim(']')
+}
+
+// ReadHeader reads the root fields preceding the conversations, so that
+// UserId and ExportDate are known before the first call to Next
+func (s *HistoryStream) ReadHeader() error {
+	if s.state != streamStart {
+		return nil
+	}
+	return s.readHeader()
+}
+
+// Owner reads the header if needed and returns UserId, the owner of the
+// export
+func (s *HistoryStream) Owner() (string, error) {
+	if err := s.ReadHeader(); err != nil {
+		return "", err
+	}
+	return s.UserId, nil
+}
+
+// readHeader consumes the root object up to the start of the
+// conversations array
+func (s *HistoryStream) readHeader() error {
+	if err := s.expectDelim('{'); err != nil {
+		return err
+	}
+
+	if err := s.readFields(); err != nil {
+		return err
+	}
+
+	if s.state != streamInConversations {
+		s.state = streamDone
+	}
+	return nil
+}
+
+// readFields reads root object fields until the conversations array
+// starts or the root object ends
+func (s *HistoryStream) readFields() error {
+	for s.decoder.More() {
+		token, err := s.decoder.Token()
+		if err != nil {
+			return fmt.Errorf("failed to parse JSON: %w", err)
+		}
+		key, ok := token.(string)
+		if !ok {
+			return fmt.Errorf("failed to parse JSON: unexpected token %v", token)
+		}
+
+		switch strings.ToLower(key) {
+		case "userid":
+			err = s.decoder.Decode(&s.UserId)
+		case "exportdate":
+			err = s.decoder.Decode(&s.ExportDate)
+		case "conversations":
+			if s.state == streamStart {
+				token, err := s.decoder.Token()
+				if err != nil {
+					return fmt.Errorf("failed to parse JSON: %w", err)
+				}
+				if token == nil {
+					// "conversations": null
+					continue
+				}
+				if delim, ok := token.(json.Delim); !ok || delim != '[' {
+					return fmt.Errorf("failed to parse JSON: conversations is not an array")
+				}
+				s.state = streamInConversations
+				return nil
+			}
+			err = s.skipValue()
+		default:
+			err = s.skipValue()
+		}
+		if err != nil {
+			return fmt.Errorf("failed to parse field %q: %w", key, err)
+		}
+	}
+
+	// Consume the closing brace of the root object
+	return s.expectDelim('}')
+}
+
+// skipValue discards the next JSON value without materializing it
+func (s *HistoryStream) skipValue() error {
+	depth := 0
+	for {
+		token, err := s.decoder.Token()
+		if err != nil {
+			return err
+		}
+		if delim, ok := token.(json.Delim); ok {
+			switch delim {
+			case '{', '[':
+				depth++
+			case '}', ']':
+				depth--
+			}
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
+
+// expectDelim consumes the next token and checks it is the given delimiter
+func (s *HistoryStream) expectDelim(want json.Delim) error {
+	token, err := s.decoder.Token()
+	if err != nil {
+		return fmt.Errorf("failed to parse JSON: %w", err)
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != want {
+		return fmt.Errorf("failed to parse JSON: expected %q, got %v", want, token)
+	}
+	return nil
+}
+
+// StreamSkypeHistory calls fn for every conversation in the export while
+// keeping only one conversation in memory at a time
+func StreamSkypeHistory(path string, options LoadOptions, fn func(conv *models.SkypeConversation) error) error {
+	stream, err := OpenHistoryStream(path, options)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	for {
+		conv, err := stream.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := fn(conv); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ExportFile gives random access to conversations and messages of an
+// export through offsets recorded by HistoryStream
+type ExportFile struct {
+	file       *os.File
+	dataOffset int64
+}
+
+// OpenExportFile opens the file holding messages.json, which starts at
+// dataOffset within it
+func OpenExportFile(sourceFile string, dataOffset int64) (*ExportFile, error) {
+	file, err := os.Open(sourceFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	return &ExportFile{file: file, dataOffset: dataOffset}, nil
+}
+
+// ReadConversation decodes the conversation at the given offset
+func (f *ExportFile) ReadConversation(offset int64) (*models.SkypeConversation, error) {
+	var conv models.SkypeConversation
+	if err := f.decodeAt(offset, &conv); err != nil {
+		return nil, fmt.Errorf("failed to read conversation: %w", err)
+	}
+	return &conv, nil
+}
+
+// ReadMessage decodes the message at the given offset
+func (f *ExportFile) ReadMessage(offset int64) (*models.SkypeMessage, error) {
+	var msg models.SkypeMessage
+	if err := f.decodeAt(offset, &msg); err != nil {
+		return nil, fmt.Errorf("failed to read message: %w", err)
+	}
+	return &msg, nil
+}
+
+// Close releases the underlying file
+func (f *ExportFile) Close() error {
+	return f.file.Close()
+}
+
+// decodeAt decodes the JSON value found at offset, skipping the separator
+// that may precede it
+func (f *ExportFile) decodeAt(offset int64, v interface{}) error {
+	if _, err := f.file.Seek(f.dataOffset+offset, io.SeekStart); err != nil {
+		return err
+	}
+
+	reader := bufio.NewReader(f.file)
+	for {
+		b, err := reader.ReadByte()
+		if err != nil {
+			return err
+		}
+		if b != ',' && b != ' ' && b != '\t' && b != '\r' && b != '\n' {
+			reader.UnreadByte()
+			break
+		}
+	}
+
+	return json.NewDecoder(reader).Decode(v)
+}
@@ -0,0 +1,238 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/beckxie/skype-history-viewer-cli/pkg/models"
+	"github.com/beckxie/skype-history-viewer-cli/pkg/progress"
+)
+
+func TestHistoryStream(t *testing.T) {
+	jsonContent := `{
+		"userId": "stream-user",
+		"extra": {"nested": [1, 2, {"deep": true}]},
+		"conversations": [
+			{"id": "conv1", "MessageList": [{"id": "m1"}, {"id": "m2"}]},
+			{"id": "conv2", "MessageList": []}
+		],
+		"exportDate": "2024-01-01T00:00:00Z"
+	}`
+
+	stream := NewHistoryStream(strings.NewReader(jsonContent))
+
+	var ids []string
+	for {
+		conv, err := stream.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next() error = %v", err)
+		}
+		ids = append(ids, conv.Id)
+	}
+
+	if len(ids) != 2 || ids[0] != "conv1" || ids[1] != "conv2" {
+		t.Errorf("unexpected conversations: %v", ids)
+	}
+	if stream.UserId != "stream-user" {
+		t.Errorf("expected userId stream-user, got %s", stream.UserId)
+	}
+	if stream.ExportDate != "2024-01-01T00:00:00Z" {
+		t.Errorf("expected trailing exportDate to be read, got %q", stream.ExportDate)
+	}
+
+	// Further calls keep returning EOF
+	if _, err := stream.Next(); err != io.EOF {
+		t.Errorf("expected io.EOF after end, got %v", err)
+	}
+}
+
+func TestHistoryStreamReadHeader(t *testing.T) {
+	jsonContent := `{"userId": "owner", "conversations": [{"id": "conv1", "MessageList": []}]}`
+	stream := NewHistoryStream(strings.NewReader(jsonContent))
+
+	if err := stream.ReadHeader(); err != nil {
+		t.Fatalf("ReadHeader() error = %v", err)
+	}
+	if stream.UserId != "owner" {
+		t.Errorf("expected userId owner before the first conversation, got %q", stream.UserId)
+	}
+
+	// Reading the header again is a no-op and conversations still follow
+	if err := stream.ReadHeader(); err != nil {
+		t.Fatalf("second ReadHeader() error = %v", err)
+	}
+	if conv, err := stream.Next(); err != nil || conv.Id != "conv1" {
+		t.Errorf("expected conv1, got %v, %v", conv, err)
+	}
+}
+
+func TestHistoryStreamWithoutConversations(t *testing.T) {
+	for _, jsonContent := range []string{
+		`{"userId": "u"}`,
+		`{"userId": "u", "conversations": null}`,
+	} {
+		stream := NewHistoryStream(strings.NewReader(jsonContent))
+		if _, err := stream.Next(); err != io.EOF {
+			t.Errorf("%s: expected io.EOF, got %v", jsonContent, err)
+		}
+	}
+}
+
+func TestHistoryStreamInvalidJSON(t *testing.T) {
+	for _, jsonContent := range []string{
+		`[]`,
+		`{"conversations": {}}`,
+		`{"conversations": [{"id": 1}]}`,
+	} {
+		stream := NewHistoryStream(strings.NewReader(jsonContent))
+		if _, err := stream.Next(); err == nil || err == io.EOF {
+			t.Errorf("%s: expected parse error, got %v", jsonContent, err)
+		}
+	}
+}
+
+func TestStreamSkypeHistory(t *testing.T) {
+	tmpDir := t.TempDir()
+	jsonPath := filepath.Join(tmpDir, "messages.json")
+	jsonContent := `{"userId": "u", "conversations": [
+		{"id": "a", "MessageList": [{"id": "m1", "from": "x", "originalarrivaltime": "2024-01-01T10:00:00Z"}]},
+		{"id": "b", "MessageList": [{"id": "m2", "from": "y", "originalarrivaltime": "2024-01-02T10:00:00Z"}]}
+	]}`
+	if err := os.WriteFile(jsonPath, []byte(jsonContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	collector := NewStatsCollector()
+	reporter := &recordingReporter{}
+	err := StreamSkypeHistory(tmpDir, LoadOptions{Reporter: reporter}, func(conv *models.SkypeConversation) error {
+		collector.Add(conv)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("StreamSkypeHistory error = %v", err)
+	}
+
+	stats := collector.Stats()
+	if stats["total_conversations"] != 2 || stats["total_messages"] != 2 || stats["total_users"] != 2 {
+		t.Errorf("unexpected stats: %v", stats)
+	}
+	if stats["first_message_date"] != "2024-01-01" || stats["last_message_date"] != "2024-01-02" {
+		t.Errorf("unexpected date range: %v - %v", stats["first_message_date"], stats["last_message_date"])
+	}
+
+	// Progress goes to the reporter, counting every byte of the file
+	size := len(jsonContent)
+	want := []string{"started", fmt.Sprintf("progress %d/%d", size, size), "finished u 2/2"}
+	if !reflect.DeepEqual(reporter.events, want) {
+		t.Errorf("expected events %v, got %v", want, reporter.events)
+	}
+}
+
+func TestLoadSkypeHistoryReportsBytes(t *testing.T) {
+	jsonPath := filepath.Join(t.TempDir(), "messages.json")
+	jsonContent := `{"userId": "u", "conversations": [{"id": "a", "MessageList": [{"id": "m1"}]}]}` + strings.Repeat(" ", 10000)
+	if err := os.WriteFile(jsonPath, []byte(jsonContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	reporter := &recordingReporter{}
+	if _, err := LoadSkypeHistory(jsonPath, LoadOptions{Reporter: reporter}); err != nil {
+		t.Fatal(err)
+	}
+
+	if reporter.events[0] != "started" || reporter.events[len(reporter.events)-1] != "finished u 1/1" {
+		t.Fatalf("unexpected events %v", reporter.events)
+	}
+	if reporter.bytesRead == 0 || reporter.bytesRead > int64(len(jsonContent)) {
+		t.Errorf("expected at most %d bytes reported, got %d", len(jsonContent), reporter.bytesRead)
+	}
+}
+
+// recordingReporter keeps the load events it receives
+type recordingReporter struct {
+	events    []string
+	bytesRead int64
+}
+
+func (r *recordingReporter) LoadStarted(source string, size int64) {
+	r.events = append(r.events, "started")
+}
+
+// LoadProgress records a single event for consecutive reads
+func (r *recordingReporter) LoadProgress(bytesRead, size int64, conversations int) {
+	event := fmt.Sprintf("progress %d/%d", bytesRead, size)
+	if last := len(r.events) - 1; last >= 0 && strings.HasPrefix(r.events[last], "progress") {
+		r.events[last] = event
+	} else {
+		r.events = append(r.events, event)
+	}
+	r.bytesRead = bytesRead
+}
+
+func (r *recordingReporter) LoadFinished(summary progress.LoadSummary) {
+	r.events = append(r.events, fmt.Sprintf("finished %s %d/%d", summary.UserId, summary.Conversations, summary.Messages))
+}
+
+func (r *recordingReporter) SearchProgress(done, total int64, unit string) {}
+
+func (r *recordingReporter) SearchFinished() {}
+
+func TestExportFileReadAtOffsets(t *testing.T) {
+	jsonPath := filepath.Join(t.TempDir(), "messages.json")
+	jsonContent := `{"conversations": [
+		{"id": "a", "MessageList": [{"id": "m1", "content": "first"},
+			{"id": "m2", "content": "second"}]} ,
+		{"id": "b", "MessageList": [{"id": "m3", "content": "third"}]}
+	]}`
+	if err := os.WriteFile(jsonPath, []byte(jsonContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	stream, err := OpenHistoryStream(jsonPath, LoadOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+
+	var offsets []ConversationOffsets
+	for {
+		_, err := stream.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		offsets = append(offsets, stream.Offsets())
+	}
+
+	file, err := OpenExportFile(stream.SourceFile(), stream.DataOffset())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	conv, err := file.ReadConversation(offsets[1].Conversation)
+	if err != nil {
+		t.Fatalf("ReadConversation error = %v", err)
+	}
+	if conv.Id != "b" {
+		t.Errorf("expected conversation b, got %s", conv.Id)
+	}
+
+	msg, err := file.ReadMessage(offsets[0].Messages[1])
+	if err != nil {
+		t.Fatalf("ReadMessage error = %v", err)
+	}
+	if msg.Content != "second" {
+		t.Errorf("expected second message, got %q", msg.Content)
+	}
+}
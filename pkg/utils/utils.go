@@ -3,124 +3,83 @@ package utils
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
-	"path/filepath"
-	"strings"
 	"time"
 
 	"github.com/beckxie/skype-history-viewer-cli/pkg/models"
+	"github.com/beckxie/skype-history-viewer-cli/pkg/progress"
 	"github.com/fatih/color"
 )
 
-// LoadSkypeHistory loads Skype history from a JSON file
-func LoadSkypeHistory(path string) (*models.SkypeHistoryRoot, error) {
-	// Check if path is a directory
-	info, err := os.Stat(path)
-	if err != nil {
-		return nil, fmt.Errorf("failed to access path: %w", err)
-	}
-
-	var jsonPath string
-	if info.IsDir() {
-		// Look for messages.json in the directory
-		jsonPath = filepath.Join(path, "messages.json")
-		if _, err := os.Stat(jsonPath); os.IsNotExist(err) {
-			return nil, fmt.Errorf("messages.json not found in directory: %s", path)
-		}
-	} else {
-		jsonPath = path
-	}
-
-	// Open file
-	file, err := os.Open(jsonPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open file: %w", err)
-	}
-	defer file.Close()
+// LoadOptions controls how an export is read
+type LoadOptions struct {
+	Reporter progress.Reporter // receives load progress, silent when nil
+}
 
-	// Get file size for progress
-	fileInfo, err := file.Stat()
+// LoadSkypeHistory loads Skype history from a JSON file, an export
+// directory or an export .tar archive
+func LoadSkypeHistory(path string, options LoadOptions) (*models.SkypeHistoryRoot, error) {
+	source, err := openExportSource(path)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get file info: %w", err)
+		return nil, err
 	}
-	fileSize := fileInfo.Size()
+	defer source.Close()
 
-	// Show loading progress
-	fmt.Println()
-	color.New(color.FgCyan).Printf("Loading Skype history from: %s\n", jsonPath)
-	color.New(color.FgYellow).Printf("File size: %.2f MB\n", float64(fileSize)/(1024*1024))
+	reporter := progress.Or(options.Reporter)
+	reporter.LoadStarted(source.name, source.size)
 
 	// For large files, use streaming decoder
-	if fileSize > 100*1024*1024 { // If file is larger than 100MB
-		fmt.Println("\nLarge file detected, using streaming decoder...")
-		return loadLargeSkypeHistory(file)
+	if source.size > 100*1024*1024 { // If file is larger than 100MB
+		return loadLargeSkypeHistory(source, reporter)
 	}
 
 	// Parse JSON directly from file to avoid extra in-memory copy of entire JSON payload.
-	fmt.Print("\nParsing JSON data...")
-	decoder := json.NewDecoder(file)
+	decoder := json.NewDecoder(&countingReader{r: source, onRead: func(bytesRead int64) {
+		reporter.LoadProgress(bytesRead, source.size, 0)
+	}})
 	var history models.SkypeHistoryRoot
 	if err := decoder.Decode(&history); err != nil {
 		return nil, fmt.Errorf("failed to parse JSON: %w", err)
 	}
 
-	fmt.Println(" Done!")
-	printLoadSummary(&history)
+	reporter.LoadFinished(loadSummary(&history))
 	return &history, nil
 }
 
-// loadLargeSkypeHistory loads large Skype history files using streaming
-func loadLargeSkypeHistory(file *os.File) (*models.SkypeHistoryRoot, error) {
-	fmt.Print("Parsing JSON data (this may take a while)...")
-
-	// Use JSON decoder for streaming
-	decoder := json.NewDecoder(file)
-
-	// Create progress ticker
-	ticker := time.NewTicker(1 * time.Second)
-	defer ticker.Stop()
-
-	done := make(chan bool)
-	go func() {
-		dots := 0
-		for {
-			select {
-			case <-ticker.C:
-				dots = (dots + 1) % 4
-				fmt.Printf("\rParsing JSON data%s   ", strings.Repeat(".", dots))
-			case <-done:
-				return
-			}
-		}
-	}()
+// loadLargeSkypeHistory loads large Skype history files one conversation
+// at a time; the stream reports progress as it goes
+func loadLargeSkypeHistory(source *exportSource, reporter progress.Reporter) (*models.SkypeHistoryRoot, error) {
+	stream := newReportingStream(source, reporter)
+	history := &models.SkypeHistoryRoot{}
 
-	// Decode JSON
-	var history models.SkypeHistoryRoot
-	if err := decoder.Decode(&history); err != nil {
-		done <- true
-		return nil, fmt.Errorf("failed to parse JSON: %w", err)
+	for {
+		conv, err := stream.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse JSON: %w", err)
+		}
+		history.Conversations = append(history.Conversations, *conv)
 	}
 
-	done <- true
-
-	fmt.Println(" Done!")
-	printLoadSummary(&history)
-	return &history, nil
+	history.UserId = stream.UserId
+	history.ExportDate = stream.ExportDate
+	return history, nil
 }
 
-func printLoadSummary(history *models.SkypeHistoryRoot) {
-	fmt.Println()
-	color.New(color.FgGreen, color.Bold).Println("✓ Successfully loaded Skype history")
-	fmt.Printf("  User ID: %s\n", history.UserId)
-	fmt.Printf("  Export Date: %s\n", history.ExportDate)
-	fmt.Printf("  Conversations: %d\n", len(history.Conversations))
-
+func loadSummary(history *models.SkypeHistoryRoot) progress.LoadSummary {
 	totalMessages := 0
 	for _, conv := range history.Conversations {
 		totalMessages += len(conv.MessageList)
 	}
-	fmt.Printf("  Total Messages: %d\n", totalMessages)
-	fmt.Println()
+	return progress.LoadSummary{
+		UserId:        history.UserId,
+		ExportDate:    history.ExportDate,
+		Conversations: len(history.Conversations),
+		Messages:      totalMessages,
+	}
 }
 
 // ExportConversation exports a conversation to JSON
@@ -150,6 +109,34 @@ func ExportConversation(conv *models.SkypeConversation, outputPath string, userI
 	return nil
 }
 
+// SaveSkypeHistory writes a whole history in the export format, encoding
+// straight to the file, and returns the number of bytes written
+func SaveSkypeHistory(history *models.SkypeHistoryRoot, outputPath string) (int64, error) {
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create file: %w", err)
+	}
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(history); err != nil {
+		file.Close()
+		return 0, fmt.Errorf("failed to write history: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return 0, fmt.Errorf("failed to get file info: %w", err)
+	}
+
+	if err := file.Close(); err != nil {
+		return 0, fmt.Errorf("failed to write history: %w", err)
+	}
+	return info.Size(), nil
+}
+
 // ParseDateString parses a date string in various formats
 func ParseDateString(dateStr string) (*time.Time, error) {
 	// Try common date formats
@@ -198,54 +185,79 @@ func TruncateString(s string, maxLen int) string {
 	return s[:maxLen-3] + "..."
 }
 
-// GetStats generates statistics for Skype history
-func GetStats(history *models.SkypeHistoryRoot) map[string]interface{} {
-	stats := make(map[string]interface{})
-
-	// Basic counts
-	stats["total_conversations"] = len(history.Conversations)
-
-	totalMessages := 0
-	totalUsers := make(map[string]bool)
-	messageTypes := make(map[string]int)
+// StatsCollector accumulates statistics one conversation at a time so
+// that they can be computed while streaming an export
+type StatsCollector struct {
+	conversations    int
+	totalMessages    int
+	totalUsers       map[string]bool
+	messageTypes     map[string]int
+	firstMessageTime *time.Time
+	lastMessageTime  *time.Time
+}
 
-	var firstMessageTime, lastMessageTime *time.Time
+// NewStatsCollector creates an empty statistics collector
+func NewStatsCollector() *StatsCollector {
+	return &StatsCollector{
+		totalUsers:   make(map[string]bool),
+		messageTypes: make(map[string]int),
+	}
+}
 
-	for _, conv := range history.Conversations {
-		for _, msg := range conv.MessageList {
-			totalMessages++
-			totalUsers[msg.From] = true
-			messageTypes[msg.MessageType]++
-
-			// Track first and last messages
-			if msgTime, err := msg.GetTimestamp(); err == nil {
-				if firstMessageTime == nil || msgTime.Before(*firstMessageTime) {
-					t := msgTime
-					firstMessageTime = &t
-				}
-				if lastMessageTime == nil || msgTime.After(*lastMessageTime) {
-					t := msgTime
-					lastMessageTime = &t
-				}
+// Add folds a conversation into the statistics
+func (c *StatsCollector) Add(conv *models.SkypeConversation) {
+	c.conversations++
+
+	for i := range conv.MessageList {
+		msg := &conv.MessageList[i]
+		c.totalMessages++
+		c.totalUsers[msg.From] = true
+		c.messageTypes[msg.MessageType]++
+
+		// Track first and last messages
+		if msgTime, err := msg.GetTimestamp(); err == nil {
+			if c.firstMessageTime == nil || msgTime.Before(*c.firstMessageTime) {
+				t := msgTime
+				c.firstMessageTime = &t
+			}
+			if c.lastMessageTime == nil || msgTime.After(*c.lastMessageTime) {
+				t := msgTime
+				c.lastMessageTime = &t
 			}
 		}
 	}
+}
+
+// Stats returns the collected statistics in the format used by DisplayStats
+func (c *StatsCollector) Stats() map[string]interface{} {
+	stats := make(map[string]interface{})
 
-	stats["total_messages"] = totalMessages
-	stats["total_users"] = len(totalUsers)
-	stats["message_types"] = messageTypes
+	// Basic counts
+	stats["total_conversations"] = c.conversations
+	stats["total_messages"] = c.totalMessages
+	stats["total_users"] = len(c.totalUsers)
+	stats["message_types"] = c.messageTypes
 
 	// Date range
-	if firstMessageTime != nil {
-		stats["first_message_date"] = firstMessageTime.Format("2006-01-02")
+	if c.firstMessageTime != nil {
+		stats["first_message_date"] = c.firstMessageTime.Format("2006-01-02")
 	}
-	if lastMessageTime != nil {
-		stats["last_message_date"] = lastMessageTime.Format("2006-01-02")
+	if c.lastMessageTime != nil {
+		stats["last_message_date"] = c.lastMessageTime.Format("2006-01-02")
 	}
 
 	return stats
 }
 
+// GetStats generates statistics for Skype history
+func GetStats(history *models.SkypeHistoryRoot) map[string]interface{} {
+	collector := NewStatsCollector()
+	for i := range history.Conversations {
+		collector.Add(&history.Conversations[i])
+	}
+	return collector.Stats()
+}
+
 // DisplayStats shows statistics in a formatted way
 func DisplayStats(stats map[string]interface{}) {
 	fmt.Println()
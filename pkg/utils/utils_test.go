@@ -114,7 +114,7 @@ func TestLoadSkypeHistory(t *testing.T) {
 	}
 
 	// Test loading from file
-	history, err := LoadSkypeHistory(jsonPath)
+	history, err := LoadSkypeHistory(jsonPath, LoadOptions{})
 	if err != nil {
 		t.Errorf("LoadSkypeHistory(file) error = %v", err)
 	} else {
@@ -124,7 +124,7 @@ func TestLoadSkypeHistory(t *testing.T) {
 	}
 
 	// Test loading from directory
-	history, err = LoadSkypeHistory(tmpDir)
+	history, err = LoadSkypeHistory(tmpDir, LoadOptions{})
 	if err != nil {
 		t.Errorf("LoadSkypeHistory(dir) error = %v", err)
 	} else if len(history.Conversations) != 1 {
@@ -132,7 +132,7 @@ func TestLoadSkypeHistory(t *testing.T) {
 	}
 
 	// Test non-existent path
-	_, err = LoadSkypeHistory(filepath.Join(tmpDir, "non-existent"))
+	_, err = LoadSkypeHistory(filepath.Join(tmpDir, "non-existent"), LoadOptions{})
 	if err == nil {
 		t.Error("expected error for non-existent path")
 	}
@@ -207,6 +207,32 @@ func TestExportConversation(t *testing.T) {
 		t.Error("exported conversation data mismatch")
 	}
 }
+func TestSaveSkypeHistory(t *testing.T) {
+	history := &models.SkypeHistoryRoot{
+		UserId: "user-1",
+		Conversations: []models.SkypeConversation{
+			{Id: "c1", MessageList: []models.SkypeMessage{{OriginalId: "m1", Content: "<b>a & b</b>"}}},
+		},
+	}
+
+	outputPath := filepath.Join(t.TempDir(), "merged.json")
+	size, err := SaveSkypeHistory(history, outputPath)
+	if err != nil {
+		t.Fatalf("SaveSkypeHistory error = %v", err)
+	}
+	if size == 0 {
+		t.Error("expected non-zero size")
+	}
+
+	loaded, err := LoadSkypeHistory(outputPath, LoadOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.UserId != "user-1" || loaded.Conversations[0].MessageList[0].Content != "<b>a & b</b>" {
+		t.Errorf("saved history does not round-trip: %+v", loaded)
+	}
+}
+
 func TestDisplayStats(t *testing.T) {
 	stats := map[string]interface{}{
 		"total_conversations": 5,
@@ -0,0 +1,205 @@
+package viewer
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/beckxie/skype-history-viewer-cli/pkg/models"
+)
+
+// Search result output formats
+const (
+	OutputText   = "text"   // colored layout of DisplaySearchResults
+	OutputJSON   = "json"   // one JSON array
+	OutputNDJSON = "ndjson" // one JSON object per line
+	OutputCSV    = "csv"    // RFC 4180 with a header row
+	OutputTSV    = "tsv"    // tab-separated with a header row, escaping tabs and newlines
+)
+
+// OutputFormats lists the accepted output formats
+var OutputFormats = []string{OutputText, OutputJSON, OutputNDJSON, OutputCSV, OutputTSV}
+
+// SearchRecord is the machine-readable form of a search result
+type SearchRecord struct {
+	ConversationId   string             `json:"conversation_id"`
+	ConversationName string             `json:"conversation_name"`
+	MessageId        string             `json:"message_id"`
+	SenderId         string             `json:"sender_id"`
+	SenderName       string             `json:"sender_name"`
+	Timestamp        string             `json:"timestamp"` // RFC 3339 in UTC, empty when unreadable
+	MessageType      string             `json:"message_type"`
+	Text             string             `json:"text"` // quoted messages first, as lines starting with "> "
+	Quotes           []QuoteRecord      `json:"quotes,omitempty"`
+	Mentions         []string           `json:"mentions,omitempty"` // ids of the members mentioned
+	Attachments      []AttachmentRecord `json:"attachments,omitempty"`
+	MatchType        string             `json:"match_type"`
+	Exports          []string           `json:"exports,omitempty"` // only when several exports were searched
+}
+
+// AttachmentRecord is the machine-readable form of an attached file
+type AttachmentRecord struct {
+	Kind         string `json:"kind"`
+	Name         string `json:"name,omitempty"`
+	Size         int64  `json:"size,omitempty"` // in bytes
+	AmsId        string `json:"ams_id,omitempty"`
+	URI          string `json:"uri,omitempty"`
+	ThumbnailURL string `json:"thumbnail_url,omitempty"`
+}
+
+// QuoteRecord is the machine-readable form of a quoted message
+type QuoteRecord struct {
+	AuthorId   string `json:"author_id"`
+	AuthorName string `json:"author_name"`
+	Timestamp  string `json:"timestamp"` // RFC 3339 in UTC, empty when unknown
+	MessageId  string `json:"message_id,omitempty"`
+	Text       string `json:"text"`
+}
+
+// recordHeader names the columns of CSV and TSV output
+var recordHeader = []string{
+	"conversation_id", "conversation_name", "message_id", "sender_id", "sender_name",
+	"timestamp", "message_type", "text", "match_type",
+}
+
+// NewSearchRecord flattens a search result into plain text fields
+func NewSearchRecord(result *SearchResult) SearchRecord {
+	msg := &result.Message
+	timestamp := ""
+	if t, err := msg.GetTimestamp(); err == nil {
+		timestamp = t.UTC().Format(time.RFC3339Nano)
+	}
+
+	return SearchRecord{
+		ConversationId:   result.ConversationId,
+		ConversationName: result.ConversationName,
+		MessageId:        msg.OriginalId,
+		SenderId:         msg.From,
+		SenderName:       msg.GetSenderDisplayName(),
+		Timestamp:        timestamp,
+		MessageType:      msg.MessageType,
+		Text:             msg.GetDisplayText(),
+		Quotes:           quoteRecords(msg.Quotes()),
+		Mentions:         msg.MentionedIds(),
+		Attachments:      attachmentRecords(msg.Attachments()),
+		MatchType:        result.MatchType,
+		Exports:          result.Exports,
+	}
+}
+
+// attachmentRecords flattens attached files, returning nil when there are
+// none
+func attachmentRecords(attachments []models.Attachment) []AttachmentRecord {
+	var records []AttachmentRecord
+	for _, attachment := range attachments {
+		records = append(records, AttachmentRecord{
+			Kind:         attachment.Kind,
+			Name:         attachment.Name,
+			Size:         attachment.Size,
+			AmsId:        attachment.AmsId,
+			URI:          attachment.URI,
+			ThumbnailURL: attachment.ThumbnailURL,
+		})
+	}
+	return records
+}
+
+// quoteRecords flattens quoted messages, returning nil when there are none
+func quoteRecords(quotes []models.Quote) []QuoteRecord {
+	var records []QuoteRecord
+	for _, quote := range quotes {
+		timestamp := ""
+		if !quote.Time.IsZero() {
+			timestamp = quote.Time.UTC().Format(time.RFC3339)
+		}
+		records = append(records, QuoteRecord{
+			AuthorId:   quote.Author,
+			AuthorName: quote.AuthorDisplayName(),
+			Timestamp:  timestamp,
+			MessageId:  quote.MessageId,
+			Text:       quote.Text,
+		})
+	}
+	return records
+}
+
+// fields returns the columns of the record, with the exports joined by
+// semicolons when withExports is set
+func (r SearchRecord) fields(withExports bool) []string {
+	fields := []string{
+		r.ConversationId, r.ConversationName, r.MessageId, r.SenderId, r.SenderName,
+		r.Timestamp, r.MessageType, r.Text, r.MatchType,
+	}
+	if withExports {
+		fields = append(fields, strings.Join(r.Exports, ";"))
+	}
+	return fields
+}
+
+// WriteSearchResults writes results to w in one of the machine-readable
+// formats, without colors. Results of several exports get an exports column.
+func WriteSearchResults(w io.Writer, results []SearchResult, format string) error {
+	records := make([]SearchRecord, len(results))
+	withExports := false
+	for i := range results {
+		records[i] = NewSearchRecord(&results[i])
+		withExports = withExports || len(records[i].Exports) > 0
+	}
+
+	header := recordHeader
+	if withExports {
+		header = append(slices.Clip(recordHeader), "exports")
+	}
+
+	switch format {
+	case OutputJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(records)
+
+	case OutputNDJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetEscapeHTML(false)
+		for _, record := range records {
+			if err := encoder.Encode(record); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case OutputCSV:
+		writer := csv.NewWriter(w)
+		writer.Write(header)
+		for _, record := range records {
+			writer.Write(record.fields(withExports))
+		}
+		writer.Flush()
+		return writer.Error()
+
+	case OutputTSV:
+		var b strings.Builder
+		b.WriteString(strings.Join(header, "\t"))
+		b.WriteByte('\n')
+		for _, record := range records {
+			fields := record.fields(withExports)
+			for i, field := range fields {
+				fields[i] = tsvEscaper.Replace(field)
+			}
+			b.WriteString(strings.Join(fields, "\t"))
+			b.WriteByte('\n')
+		}
+		_, err := io.WriteString(w, b.String())
+		return err
+
+	default:
+		return fmt.Errorf("unsupported output format %q", format)
+	}
+}
+
+// tsvEscaper keeps every TSV record on one line
+var tsvEscaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
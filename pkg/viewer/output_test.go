@@ -0,0 +1,212 @@
+package viewer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/beckxie/skype-history-viewer-cli/pkg/models"
+)
+
+func testSearchResults() []SearchResult {
+	return []SearchResult{
+		{
+			ConversationId:   "19:team@thread.skype",
+			ConversationName: "Team",
+			Message: models.SkypeMessage{
+				OriginalId:  "m1",
+				DisplayName: stringPtr("Alice"),
+				From:        "8:alice",
+				Content:     "<b>Ship</b> it &amp; go\tnow",
+				MessageType: "RichText",
+				Timestamp:   "2024-01-01T10:00:00.5Z",
+			},
+			MatchContext: "\x1b[33mShip\x1b[0m it",
+			MatchType:    "content",
+		},
+		{
+			ConversationName: "Other",
+			Message: models.SkypeMessage{
+				OriginalId: "m2",
+				From:       "8:bob",
+				Content:    "line one\nline, two",
+				Timestamp:  "invalid",
+			},
+			MatchType: "both",
+		},
+	}
+}
+
+func TestNewSearchRecord(t *testing.T) {
+	results := testSearchResults()
+	record := NewSearchRecord(&results[0])
+
+	want := SearchRecord{
+		ConversationId:   "19:team@thread.skype",
+		ConversationName: "Team",
+		MessageId:        "m1",
+		SenderId:         "8:alice",
+		SenderName:       "Alice",
+		Timestamp:        "2024-01-01T10:00:00.5Z",
+		MessageType:      "RichText",
+		Text:             "Ship it & go\tnow",
+		MatchType:        "content",
+	}
+	if !reflect.DeepEqual(record, want) {
+		t.Errorf("NewSearchRecord() = %+v, want %+v", record, want)
+	}
+
+	if record := NewSearchRecord(&results[1]); record.Timestamp != "" || record.SenderName != "8:bob" {
+		t.Errorf("unexpected record for invalid timestamp: %+v", record)
+	}
+}
+
+func TestWriteSearchResults(t *testing.T) {
+	results := testSearchResults()
+
+	write := func(format string) string {
+		t.Helper()
+		var buf bytes.Buffer
+		if err := WriteSearchResults(&buf, results, format); err != nil {
+			t.Fatalf("WriteSearchResults(%s) error = %v", format, err)
+		}
+		if strings.Contains(buf.String(), "\x1b[") {
+			t.Errorf("%s output contains ANSI codes", format)
+		}
+		return buf.String()
+	}
+
+	t.Run("json", func(t *testing.T) {
+		var records []SearchRecord
+		if err := json.Unmarshal([]byte(write(OutputJSON)), &records); err != nil {
+			t.Fatal(err)
+		}
+		if len(records) != 2 || records[0].Text != "Ship it & go\tnow" || records[1].MessageId != "m2" {
+			t.Errorf("unexpected records: %+v", records)
+		}
+	})
+
+	t.Run("ndjson", func(t *testing.T) {
+		lines := strings.Split(strings.TrimSuffix(write(OutputNDJSON), "\n"), "\n")
+		if len(lines) != 2 {
+			t.Fatalf("expected 2 lines, got %d", len(lines))
+		}
+		var record SearchRecord
+		if err := json.Unmarshal([]byte(lines[1]), &record); err != nil {
+			t.Fatal(err)
+		}
+		if record.Text != "line one\nline, two" || record.MatchType != "both" {
+			t.Errorf("unexpected record: %+v", record)
+		}
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		rows, err := csv.NewReader(strings.NewReader(write(OutputCSV))).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rows) != 3 || rows[0][0] != "conversation_id" || rows[2][7] != "line one\nline, two" {
+			t.Errorf("unexpected rows: %q", rows)
+		}
+	})
+
+	t.Run("tsv", func(t *testing.T) {
+		lines := strings.Split(strings.TrimSuffix(write(OutputTSV), "\n"), "\n")
+		if len(lines) != 3 {
+			t.Fatalf("expected a header and 2 lines, got %q", lines)
+		}
+		fields := strings.Split(lines[1], "\t")
+		if len(fields) != len(recordHeader) || fields[7] != `Ship it & go\tnow` {
+			t.Errorf("unexpected fields: %q", fields)
+		}
+	})
+
+	t.Run("exports column", func(t *testing.T) {
+		labeled := testSearchResults()
+		labeled[0].Exports = []string{"alice", "bob"}
+
+		var buf bytes.Buffer
+		if err := WriteSearchResults(&buf, labeled, OutputCSV); err != nil {
+			t.Fatal(err)
+		}
+		rows, err := csv.NewReader(&buf).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rows[0][len(rows[0])-1] != "exports" || rows[1][len(rows[1])-1] != "alice;bob" || rows[2][len(rows[2])-1] != "" {
+			t.Errorf("unexpected rows: %q", rows)
+		}
+		if len(recordHeader) != 9 {
+			t.Error("expected the shared header to be left untouched")
+		}
+
+		buf.Reset()
+		if err := WriteSearchResults(&buf, labeled, OutputNDJSON); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(buf.String(), `"exports":["alice","bob"]`) {
+			t.Errorf("expected exports in %s", buf.String())
+		}
+	})
+
+	t.Run("quotes", func(t *testing.T) {
+		quoted := testSearchResults()
+		quoted[0].Message.Content = `<quote author="8:carol" authorname="Carol" timestamp="1704103200" messageid="m0">` +
+			`<legacyquote>[1704103200] Carol: </legacyquote>Ready?<legacyquote>&lt;&lt;&lt; </legacyquote></quote>Ship it`
+
+		var buf bytes.Buffer
+		if err := WriteSearchResults(&buf, quoted[:1], OutputJSON); err != nil {
+			t.Fatal(err)
+		}
+		var records []SearchRecord
+		if err := json.Unmarshal(buf.Bytes(), &records); err != nil {
+			t.Fatal(err)
+		}
+		want := []QuoteRecord{{AuthorId: "8:carol", AuthorName: "Carol", Timestamp: "2024-01-01T10:00:00Z", MessageId: "m0", Text: "Ready?"}}
+		if records[0].Text != "> Ready?\nShip it" || !reflect.DeepEqual(records[0].Quotes, want) {
+			t.Errorf("unexpected record: %+v", records[0])
+		}
+		if strings.Contains(write(OutputNDJSON), "quotes") {
+			t.Error("expected no quotes field for messages without quotes")
+		}
+	})
+
+	t.Run("attachments", func(t *testing.T) {
+		shared := testSearchResults()
+		shared[0].Message.Content = `<URIObject type="Picture.1" uri="https://api.asm.skype.com/v1/objects/0-pic">` +
+			`<OriginalName v="cat.png"></OriginalName><FileSize v="2048"></FileSize></URIObject>`
+
+		var buf bytes.Buffer
+		if err := WriteSearchResults(&buf, shared[:1], OutputJSON); err != nil {
+			t.Fatal(err)
+		}
+		var records []SearchRecord
+		if err := json.Unmarshal(buf.Bytes(), &records); err != nil {
+			t.Fatal(err)
+		}
+		want := []AttachmentRecord{{Kind: "image", Name: "cat.png", Size: 2048, AmsId: "0-pic", URI: "https://api.asm.skype.com/v1/objects/0-pic"}}
+		if !reflect.DeepEqual(records[0].Attachments, want) {
+			t.Errorf("unexpected attachments: %+v", records[0].Attachments)
+		}
+		if strings.Contains(write(OutputNDJSON), "attachments") {
+			t.Error("expected no attachments field for messages without attachments")
+		}
+	})
+
+	t.Run("empty json is an array", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := WriteSearchResults(&buf, nil, OutputJSON); err != nil {
+			t.Fatal(err)
+		}
+		if strings.TrimSpace(buf.String()) != "[]" {
+			t.Errorf("expected [], got %q", buf.String())
+		}
+	})
+
+	if err := WriteSearchResults(&bytes.Buffer{}, results, "xml"); err == nil {
+		t.Error("expected an error for an unsupported format")
+	}
+}
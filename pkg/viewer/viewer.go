@@ -36,6 +36,16 @@ func NewMessageViewer(options ViewerOptions) *MessageViewer {
 
 // DisplayConversationList shows all conversations in a table
 func (v *MessageViewer) DisplayConversationList(conversations []models.SkypeConversation) {
+	summaries := make([]models.ConversationSummary, 0, len(conversations))
+	for i := range conversations {
+		summaries = append(summaries, conversations[i].Summary())
+	}
+	v.DisplayConversationSummaries(summaries)
+}
+
+// DisplayConversationSummaries shows pre-computed conversation summaries in
+// a table, numbered in the order given
+func (v *MessageViewer) DisplayConversationSummaries(summaries []models.ConversationSummary) {
 	// Create table
 	table := tablewriter.NewWriter(os.Stdout)
 	table.SetHeader([]string{"#", "Conversation", "Participants", "Messages", "Last Message"})
@@ -48,35 +58,21 @@ func (v *MessageViewer) DisplayConversationList(conversations []models.SkypeConv
 	table.SetAlignment(tablewriter.ALIGN_LEFT)
 
 	// Add data
-	for i, conv := range conversations {
-		messageCount := len(conv.MessageList)
+	for i, summary := range summaries {
+		messageCount := summary.MessageCount
 		if !v.options.ShowSystemMessages {
-			messageCount = len(conv.FilterSystemMessages())
+			messageCount -= summary.SystemMessageCount
 		}
 
 		lastMessage := ""
-		if len(conv.MessageList) > 0 {
-			var latest time.Time
-			hasLatest := false
-			for _, msg := range conv.MessageList {
-				t, err := msg.GetTimestamp()
-				if err != nil {
-					continue
-				}
-				if !hasLatest || t.After(latest) {
-					latest = t
-					hasLatest = true
-				}
-			}
-			if hasLatest {
-				lastMessage = latest.Format("2006-01-02 15:04")
-			}
+		if !summary.LastMessage.IsZero() {
+			lastMessage = summary.LastMessage.Format("2006-01-02 15:04")
 		}
 
 		table.Append([]string{
 			fmt.Sprintf("%d", i+1),
-			conv.GetConversationDisplayName(),
-			fmt.Sprintf("%d", conv.GetParticipantCount()),
+			summary.DisplayName,
+			fmt.Sprintf("%d", summary.ParticipantCount),
 			fmt.Sprintf("%d", messageCount),
 			lastMessage,
 		})
@@ -153,9 +149,10 @@ func (v *MessageViewer) DisplayConversation(conv *models.SkypeConversation, page
 	color.New(color.FgYellow).Printf("Page %d/%d (Messages %d-%d of %d)\n", page, totalPages, start+1, end, len(messages))
 	fmt.Println(strings.Repeat("-", 80))
 
-	// Display messages
+	// Display messages, naming members in group events after what they wrote under
+	names := conv.MemberNames()
 	for _, msg := range messages[start:end] {
-		v.DisplayMessage(&msg)
+		v.displayMessage(&msg, conv, names)
 		fmt.Println(strings.Repeat("-", 80))
 	}
 
@@ -168,6 +165,13 @@ func (v *MessageViewer) DisplayConversation(conv *models.SkypeConversation, page
 
 // DisplayMessage shows a single message
 func (v *MessageViewer) DisplayMessage(msg *models.SkypeMessage) {
+	v.displayMessage(msg, nil, nil)
+}
+
+// displayMessage shows a single message of conv, naming the members of
+// group events after names. Without conv, quotes are not linked to the
+// messages they quote.
+func (v *MessageViewer) displayMessage(msg *models.SkypeMessage, conv *models.SkypeConversation, names map[string]string) {
 	// Parse timestamp
 	timestamp := "Unknown time"
 	if t, err := msg.GetTimestamp(); err == nil {
@@ -185,15 +189,24 @@ func (v *MessageViewer) DisplayMessage(msg *models.SkypeMessage) {
 	}
 	fmt.Println()
 
-	// Display content
-	content := msg.GetDisplayText()
-	if content != "" {
-		fmt.Printf("  %s\n", content)
+	// Display content, calls and group events as a summary rather than
+	// their raw payload
+	if event, err := msg.ParseCallEvent(); err == nil {
+		displayCallEvent(event)
+	} else if event, err := msg.ParseThreadEvent(); err == nil {
+		color.New(color.FgYellow).Printf("  👥 %s\n", event.Describe(names))
+	} else {
+		for _, quote := range msg.Quotes() {
+			displayQuote(&quote, conv)
+		}
+		if text := msg.BodyText(); text != "" {
+			fmt.Printf("  %s\n", highlightMentions(text, msg.Mentions()))
+		}
 	}
 
 	// Display attachments if any
-	if len(msg.AmsReferences) > 0 {
-		color.New(color.FgYellow).Printf("  📎 %d attachment(s)\n", len(msg.AmsReferences))
+	for _, attachment := range msg.Attachments() {
+		color.New(color.FgYellow).Printf("  📎 %s\n", attachment.Describe())
 	}
 
 	// Display URL previews if any
@@ -216,24 +229,108 @@ func (v *MessageViewer) DisplaySearchResults(results []SearchResult) {
 	for i, result := range results {
 		// Display result number and conversation
 		color.New(color.FgYellow).Printf("[%d] ", i+1)
-		color.New(color.FgMagenta).Printf("In: %s\n", result.ConversationName)
+		color.New(color.FgMagenta).Printf("In: %s", result.ConversationName)
+		if len(result.Exports) > 0 {
+			color.New(color.FgBlue).Printf(" [%s]", strings.Join(result.Exports, ", "))
+		}
+		if result.Score > 0 {
+			color.New(color.FgWhite).Printf(" (relevance %.2f)", result.Score)
+		}
+		fmt.Println()
 
-		// Display message with highlighted match
+		// Display message with highlighted match, between its surrounding
+		// messages when requested
+		for j := range result.Before {
+			v.displayContextMessage(&result.Before[j])
+		}
+		if len(result.Before) > 0 || len(result.After) > 0 {
+			color.New(color.FgYellow, color.Bold).Print("▶ ")
+		}
 		v.DisplayMessage(&result.Message)
 
 		// Display match context
 		if result.MatchContext != "" {
 			color.New(color.FgGreen).Printf("  Match: %s\n", result.MatchContext)
 		}
+		if result.MatchedVariant != "" {
+			color.New(color.FgGreen).Printf("  Fuzzy match: %q (distance %d)\n", result.MatchedVariant, result.Distance)
+		}
+		for j := range result.After {
+			v.displayContextMessage(&result.After[j])
+		}
 
 		fmt.Println(strings.Repeat("-", 80))
 	}
 }
 
+// displayContextMessage shows a message surrounding a search hit as a
+// single dimmed line
+func (v *MessageViewer) displayContextMessage(msg *models.SkypeMessage) {
+	timestamp := "Unknown time"
+	if t, err := msg.GetTimestamp(); err == nil {
+		timestamp = t.Format("2006-01-02 15:04:05")
+	}
+
+	content := strings.Join(strings.Fields(msg.GetDisplayText()), " ")
+	color.New(color.Faint).Printf("  │ %s at %s: %s\n", msg.GetSenderDisplayName(), timestamp, content)
+}
+
 // SearchResult represents a search match
 type SearchResult struct {
+	ConversationId   string
 	ConversationName string
 	Message          models.SkypeMessage
 	MatchContext     string
-	MatchType        string // "content", "sender", or "both"
+	MatchType        string                // "content", "sender", "both", or "filter" for qualifier-only queries
+	Score            float64               // BM25 relevance, only set by full-text index searches
+	MatchedVariant   string                // words a fuzzy search matched, as written in the message
+	Distance         int                   // edit distance of a fuzzy match
+	Before           []models.SkypeMessage // messages preceding the hit, oldest first
+	After            []models.SkypeMessage // messages following the hit
+	Exports          []string              // labels of the exports holding the message, when several were searched
+}
+
+// displayCallEvent shows a call event, missed calls in red
+func displayCallEvent(event *models.CallEvent) {
+	c := color.New(color.FgCyan)
+	if event.Type == models.CallMissed || (event.Type == models.CallEnded && event.Duration() == 0) {
+		c = color.New(color.FgRed)
+	}
+	c.Printf("  📞 %s\n", event.Describe())
+}
+
+// displayQuote shows a quoted message as an indented, dimmed block headed
+// by its author and time, and the id of the message when conv holds it
+func displayQuote(quote *models.Quote, conv *models.SkypeConversation) {
+	header := quote.AuthorDisplayName()
+	if !quote.Time.IsZero() {
+		header += ", " + quote.Time.Format("2006-01-02 15:04:05")
+	}
+	if conv != nil {
+		if original := conv.QuotedMessage(quote); original != nil && original.OriginalId != "" {
+			header += fmt.Sprintf(" (message %s)", original.OriginalId)
+		}
+	}
+
+	dim := color.New(color.Faint)
+	dim.Printf("  │ %s\n", header)
+	for _, line := range strings.Split(quote.Text, "\n") {
+		dim.Printf("  │ %s\n", line)
+	}
+}
+
+// highlightMentions colors the "@Name" mentions of text
+func highlightMentions(text string, mentions []models.Mention) string {
+	highlight := color.New(color.FgCyan, color.Bold)
+	var replacements []string
+	for _, mention := range mentions {
+		if mention.Name != "" {
+			at := "@" + mention.Name
+			replacements = append(replacements, at, highlight.Sprint(at))
+		}
+	}
+	if len(replacements) == 0 {
+		return text
+	}
+	return strings.NewReplacer(replacements...).Replace(text)
 }
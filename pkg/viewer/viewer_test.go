@@ -146,6 +146,43 @@ func TestDisplaySearchResults(t *testing.T) {
 	}
 }
 
+func TestDisplaySearchResultsWithSurroundingMessages(t *testing.T) {
+	oldStdout := os.Stdout
+	oldColorOutput := color.Output
+	defer func() {
+		os.Stdout = oldStdout
+		color.Output = oldColorOutput
+	}()
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+	color.Output = w
+
+	v := NewMessageViewer(ViewerOptions{})
+	v.DisplaySearchResults([]SearchResult{
+		{
+			ConversationName: "Conv 1",
+			Message:          models.SkypeMessage{From: "bob", Content: "deploy failed", Timestamp: "2024-01-01T10:01:00Z"},
+			MatchContext:     "deploy failed",
+			Before:           []models.SkypeMessage{{From: "alice", Content: "good\nmorning", Timestamp: "2024-01-01T10:00:00Z"}},
+			After:            []models.SkypeMessage{{From: "carol", Content: "looking into it", Timestamp: "2024-01-01T10:02:00Z"}},
+		},
+	})
+
+	w.Close()
+	out, _ := io.ReadAll(r)
+	output := string(out)
+
+	before := strings.Index(output, "alice at 2024-01-01 10:00:00: good morning")
+	hit := strings.Index(output, "deploy failed")
+	after := strings.Index(output, "carol at 2024-01-01 10:02:00: looking into it")
+	if before == -1 || hit == -1 || after == -1 {
+		t.Fatalf("expected surrounding messages in output, got: %s", output)
+	}
+	if !(before < hit && hit < after) {
+		t.Errorf("expected surrounding messages around the hit, got: %s", output)
+	}
+}
+
 func TestDisplayConversationList(t *testing.T) {
 	// Capture stdout
 	oldStdout := os.Stdout
@@ -217,3 +254,159 @@ func TestDisplayConversationListDoesNotMutateOrder(t *testing.T) {
 func stringPtr(s string) *string {
 	return &s
 }
+
+func TestDisplayMessageCallEvent(t *testing.T) {
+	capture := func(msg models.SkypeMessage) string {
+		t.Helper()
+		oldStdout := os.Stdout
+		oldColorOutput := color.Output
+		r, w, _ := os.Pipe()
+		os.Stdout = w
+		color.Output = w
+
+		NewMessageViewer(ViewerOptions{}).DisplayMessage(&msg)
+
+		w.Close()
+		os.Stdout = oldStdout
+		color.Output = oldColorOutput
+		out, _ := io.ReadAll(r)
+		return string(out)
+	}
+
+	output := capture(models.SkypeMessage{
+		MessageType: "Event/Call",
+		Timestamp:   "2024-01-01T10:00:00Z",
+		Content:     `<partlist type="ended" alt=""><part identity="8:live:alice"><name>Alice</name><duration>62</duration></part></partlist>`,
+	})
+	if !strings.Contains(output, "📞 Call ended after 1m2s with Alice") || strings.Contains(output, "partlist") {
+		t.Errorf("unexpected call rendering: %s", output)
+	}
+
+	// Call events without a participant list are shown as text
+	output = capture(models.SkypeMessage{MessageType: "Event/Call", Content: "Call ended."})
+	if !strings.Contains(output, "Call ended.") || strings.Contains(output, "📞") {
+		t.Errorf("expected the plain text fallback, got: %s", output)
+	}
+}
+
+func TestDisplayConversationThreadEvents(t *testing.T) {
+	oldStdout := os.Stdout
+	oldColorOutput := color.Output
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+	color.Output = w
+
+	conv := &models.SkypeConversation{
+		DisplayName: stringPtr("Team"),
+		MessageList: []models.SkypeMessage{
+			{OriginalId: "1", From: "8:alice", DisplayName: stringPtr("Alice"), Timestamp: "2024-01-01T10:00:00Z", Content: "Welcome"},
+			{OriginalId: "2", From: "8:bob", DisplayName: stringPtr("Bob"), Timestamp: "2024-01-01T10:01:00Z", Content: "Thanks"},
+			{OriginalId: "3", From: "8:alice", MessageType: "Control/ThreadActivity", Timestamp: "2024-01-01T10:02:00Z",
+				Content: "<addmember><initiator>8:alice</initiator><target>8:bob</target></addmember>"},
+			{OriginalId: "4", From: "8:alice", MessageType: "Control/ThreadActivity", Timestamp: "2024-01-01T10:03:00Z",
+				Content: "<topicupdate><initiator>8:alice</initiator><value>Launch</value></topicupdate>"},
+		},
+	}
+	NewMessageViewer(ViewerOptions{ShowSystemMessages: true}).DisplayConversation(conv, 1)
+
+	w.Close()
+	os.Stdout = oldStdout
+	color.Output = oldColorOutput
+
+	out, _ := io.ReadAll(r)
+	output := string(out)
+	for _, want := range []string{"👥 Alice added Bob", `👥 Alice changed the topic to "Launch"`} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %q in output: %s", want, output)
+		}
+	}
+	if strings.Contains(output, "initiator") {
+		t.Errorf("expected no raw payload in output: %s", output)
+	}
+}
+
+func TestDisplayConversationQuotes(t *testing.T) {
+	oldStdout := os.Stdout
+	oldColorOutput := color.Output
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+	color.Output = w
+
+	conv := &models.SkypeConversation{
+		DisplayName: stringPtr("Team"),
+		MessageList: []models.SkypeMessage{
+			{OriginalId: "1704103200123", From: "8:alice", Timestamp: "2024-01-01T10:00:00Z", Content: "Shipping today?"},
+			{OriginalId: "2", From: "8:bob", Timestamp: "2024-01-01T10:01:00Z",
+				Content: `<quote author="8:alice" authorname="Alice" timestamp="1704103200" messageid="1704103200123">` +
+					`<legacyquote>[1704103200] Alice: </legacyquote>Shipping today?<legacyquote>&lt;&lt;&lt; </legacyquote></quote>Yes`},
+		},
+	}
+	NewMessageViewer(ViewerOptions{}).DisplayConversation(conv, 1)
+
+	w.Close()
+	os.Stdout = oldStdout
+	color.Output = oldColorOutput
+
+	out, _ := io.ReadAll(r)
+	output := string(out)
+	for _, want := range []string{
+		"  │ Alice, 2024-01-01 10:00:00 (message 1704103200123)\n",
+		"  │ Shipping today?\n",
+		"  Yes\n",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %q in output: %s", want, output)
+		}
+	}
+	if strings.Contains(output, "legacyquote") || strings.Contains(output, "<<<") {
+		t.Errorf("expected no legacy quote in output: %s", output)
+	}
+}
+
+func TestHighlightMentions(t *testing.T) {
+	oldNoColor := color.NoColor
+	color.NoColor = false
+	defer func() { color.NoColor = oldNoColor }()
+
+	mentions := []models.Mention{{Id: "8:alice", Name: "Alice"}, {Id: "8:x"}}
+	got := highlightMentions("hi @Alice and Alice", mentions)
+	want := "hi " + color.New(color.FgCyan, color.Bold).Sprint("@Alice") + " and Alice"
+	if got != want {
+		t.Errorf("highlightMentions() = %q, want %q", got, want)
+	}
+	if got := highlightMentions("hi", nil); got != "hi" {
+		t.Errorf("highlightMentions() without mentions = %q", got)
+	}
+}
+
+func TestDisplayMessageAttachments(t *testing.T) {
+	oldStdout := os.Stdout
+	oldColorOutput := color.Output
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+	color.Output = w
+
+	NewMessageViewer(ViewerOptions{}).DisplayMessage(&models.SkypeMessage{
+		MessageType: "RichText/Media_GenericFile",
+		Timestamp:   "2024-01-01T10:00:00Z",
+		Content: `<URIObject type="File.1" uri="https://api.asm.skype.com/v1/objects/0-abc">` +
+			`To view this file, go to: <a href="https://login.skype.com/">https://login.skype.com/</a>` +
+			`<OriginalName v="report.pdf"></OriginalName><FileSize v="12645"></FileSize></URIObject>`,
+		AmsReferences: []string{"0-abc", "0-def"},
+	})
+
+	w.Close()
+	os.Stdout = oldStdout
+	color.Output = oldColorOutput
+	out, _ := io.ReadAll(r)
+	output := string(out)
+
+	for _, want := range []string{"📎 report.pdf (file, 12.3 KB)", "📎 Unnamed file (file)"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %q in output: %s", want, output)
+		}
+	}
+	if strings.Contains(output, "To view this file") || strings.Contains(output, "URIObject") {
+		t.Errorf("expected the URIObject markup to be hidden: %s", output)
+	}
+}